@@ -31,6 +31,9 @@ var musics map[MusicID]*mix.Music = make(map[MusicID]*mix.Music)
 
 var currentMusic MusicID = MUSIC_0
 
+// initialized is false when running without audio (e.g. headless), then all audio functions do nothing
+var initialized bool
+
 func InitAudio() {
 	err := mix.OpenAudio(44100, mix.DEFAULT_FORMAT, 2, 2048)
 	if err != nil {
@@ -38,6 +41,7 @@ func InitAudio() {
 	}
 
 	loadAllAudios()
+	initialized = true
 }
 
 func loadAllAudios() {
@@ -67,19 +71,74 @@ func loadAllAudios() {
 }
 
 func PlayMusic() {
+	if !initialized {
+		return
+	}
 	musics[currentMusic].Play(-1)
 }
 
 func PauseMusic() {
+	if !initialized {
+		return
+	}
 	mix.PauseMusic()
 }
 
+func ResumeMusic() {
+	if !initialized {
+		return
+	}
+	mix.ResumeMusic()
+}
+
+// PauseSounds pauses sounds being played, e.g. when the game is paused
+func PauseSounds() {
+	if !initialized {
+		return
+	}
+	mix.Pause(-1)
+}
+
+func ResumeSounds() {
+	if !initialized {
+		return
+	}
+	mix.Resume(-1)
+}
+
+// SetMusicOn turns music on or off, music keeps playing silently when off
+func SetMusicOn(on bool) {
+	if !initialized {
+		return
+	}
+	if on {
+		mix.VolumeMusic(mix.MAX_VOLUME)
+	} else {
+		mix.VolumeMusic(0)
+	}
+}
+
+// SetSoundOn turns sounds on or off
+func SetSoundOn(on bool) {
+	if !initialized {
+		return
+	}
+	if on {
+		mix.Volume(-1, mix.MAX_VOLUME)
+	} else {
+		mix.Volume(-1, 0)
+	}
+}
+
 func StopMusic() {
+	if !initialized {
+		return
+	}
 	mix.HaltMusic()
 }
 
 func ReloadMusic(mid MusicID) {
-	if mid == currentMusic {
+	if !initialized || mid == currentMusic {
 		return
 	}
 
@@ -89,6 +148,9 @@ func ReloadMusic(mid MusicID) {
 }
 
 func Destroy() {
+	if !initialized {
+		return
+	}
 	for _, s := range sounds {
 		s.Free()
 	}
@@ -104,5 +166,8 @@ func must(err error) {
 }
 
 func PlaySound(id SoundID) {
+	if !initialized {
+		return
+	}
 	sounds[id].Play(-1, 0)
 }
@@ -0,0 +1,123 @@
+package clock
+
+// Clock tells the current game time in milliseconds
+type Clock interface {
+	Ticks() uint32
+}
+
+// assert GameClock and ManualClock are clocks
+var _ Clock = &GameClock{}
+var _ Clock = &ManualClock{}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// GameClock
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// if the game falls behind more than this, the rest is dropped instead of catching up
+const maxAccumulatedMs = 250
+
+// GameClock follows a real time source (e.g. sdl.GetTicks), but can be paused and scaled
+// Real time passed is accumulated by Tick(), and game time only moves in fixed steps by Step(),
+// so that everything in one step sees the same ticks and the simulation doesn't depend on frame time
+type GameClock struct {
+	source     func() uint32
+	lastSource uint32
+
+	// game time in milliseconds
+	ticks uint32
+
+	// scaled time not yet consumed by steps, float so that a small scale won't be rounded to zero
+	accumulated float64
+
+	scale  float64
+	paused bool
+}
+
+func NewGameClock(source func() uint32) *GameClock {
+	return &GameClock{
+		source:     source,
+		lastSource: source(),
+		// start from 1 since zero ticks usually means "not set yet" for level objects
+		ticks: 1,
+		scale: 1,
+	}
+}
+
+// Tick accumulates the real time passed since last Tick, it should be called once per frame
+func (gc *GameClock) Tick() {
+	now := gc.source()
+	elapsed := now - gc.lastSource
+	gc.lastSource = now
+
+	if !gc.paused {
+		gc.accumulated += float64(elapsed) * gc.scale
+		if gc.accumulated > maxAccumulatedMs {
+			gc.accumulated = maxAccumulatedMs
+		}
+	}
+}
+
+// Step moves game time forward by stepMs if enough time has been accumulated
+// It returns false if not, so the usual usage is: for clock.Step(STEP) { update }
+func (gc *GameClock) Step(stepMs uint32) bool {
+	if gc.paused || gc.accumulated < float64(stepMs) {
+		return false
+	}
+	gc.accumulated -= float64(stepMs)
+	gc.ticks += stepMs
+	return true
+}
+
+func (gc *GameClock) Ticks() uint32 {
+	return gc.ticks
+}
+
+// Pause freezes the clock, real time passed during pause is simply lost
+func (gc *GameClock) Pause() {
+	gc.paused = true
+}
+
+func (gc *GameClock) Resume() {
+	gc.paused = false
+}
+
+func (gc *GameClock) IsPaused() bool {
+	return gc.paused
+}
+
+// SetScale sets how fast game time goes compared to real time, e.g. 0.5 means slow motion
+func (gc *GameClock) SetScale(scale float64) {
+	if scale < 0 {
+		scale = 0
+	}
+	gc.scale = scale
+}
+
+func (gc *GameClock) GetScale() float64 {
+	return gc.scale
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// ManualClock
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ManualClock only moves when being told to, it is useful for tests
+type ManualClock struct {
+	ticks uint32
+}
+
+func NewManualClock(startTicks uint32) *ManualClock {
+	return &ManualClock{ticks: startTicks}
+}
+
+func (mc *ManualClock) Ticks() uint32 {
+	return mc.ticks
+}
+
+func (mc *ManualClock) Advance(ms uint32) {
+	mc.ticks += ms
+}
+
+func (mc *ManualClock) Set(ticks uint32) {
+	mc.ticks = ticks
+}
@@ -0,0 +1,78 @@
+package clock_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+)
+
+func TestGameClock(t *testing.T) {
+	var realTicks uint32 = 5000
+	gc := clock.NewGameClock(func() uint32 { return realTicks })
+
+	start := gc.Ticks()
+
+	realTicks += 100
+	gc.Tick()
+	if gc.Ticks() != start {
+		t.Errorf("expected ticks not to move before Step() but was %d", gc.Ticks())
+	}
+	if steps := stepAll(gc, 16); steps != 6 {
+		t.Errorf("expected 6 steps in 100ms but was %d", steps)
+	}
+	if gc.Ticks() != start+96 {
+		t.Errorf("expected ticks %d but was %d", start+96, gc.Ticks())
+	}
+
+	// the 4ms left is kept for next frame
+	realTicks += 12
+	gc.Tick()
+	if steps := stepAll(gc, 16); steps != 1 {
+		t.Errorf("expected 1 step but was %d", steps)
+	}
+
+	// paused: real time is lost
+	gc.Pause()
+	realTicks += 100
+	gc.Tick()
+	if steps := stepAll(gc, 16); steps != 0 {
+		t.Errorf("expected no steps when paused but was %d", steps)
+	}
+	gc.Resume()
+
+	// half speed
+	gc.SetScale(0.5)
+	realTicks += 64
+	gc.Tick()
+	if steps := stepAll(gc, 16); steps != 2 {
+		t.Errorf("expected 2 steps with scale 0.5 but was %d", steps)
+	}
+
+	// falling far behind won't make the game catch up forever
+	gc.SetScale(1)
+	realTicks += 10000
+	gc.Tick()
+	if steps := stepAll(gc, 16); steps > 250/16 {
+		t.Errorf("expected at most %d steps but was %d", 250/16, steps)
+	}
+}
+
+func stepAll(gc *clock.GameClock, stepMs uint32) int {
+	steps := 0
+	for gc.Step(stepMs) {
+		steps++
+	}
+	return steps
+}
+
+func TestManualClock(t *testing.T) {
+	mc := clock.NewManualClock(1)
+	mc.Advance(16)
+	if mc.Ticks() != 17 {
+		t.Errorf("expected ticks 17 but was %d", mc.Ticks())
+	}
+	mc.Set(100)
+	if mc.Ticks() != 100 {
+		t.Errorf("expected ticks 100 but was %d", mc.Ticks())
+	}
+}
@@ -0,0 +1,74 @@
+// mario-lint checks level files without opening a window
+//
+// Usage (from the repo root, as assets are loaded by relative paths):
+//
+//	mario-lint [-json] [level dir ...]
+//
+// Each problem is printed in a line like "file:row:col: message", or as a JSON array with -json.
+// Exit code is 1 if any problem is found.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+)
+
+const default_level_dir = "assets/levels"
+
+var jsonOutput = flag.Bool("json", false, "print problems as a JSON array")
+
+// problem is the JSON form of a level.ParseError
+type problem struct {
+	File    string `json:"file"`
+	Row     int    `json:"row"`
+	Col     int    `json:"col"`
+	Message string `json:"message"`
+}
+
+func main() {
+	flag.Parse()
+
+	dirs := flag.Args()
+	if len(dirs) == 0 {
+		dirs = []string{default_level_dir}
+	}
+
+	// levels are built to find some problems, but nothing is rendered
+	graphic.Init(graphic.NewNullBackend())
+	defer graphic.DestroyAndQuit()
+
+	var errs level.ParseErrors
+	for _, dir := range dirs {
+		specs, err := level.LoadLevelSpecs(dir)
+		errs.Add(dir, err)
+		for _, spec := range specs {
+			errs = append(errs, level.LintLevel(spec)...)
+		}
+	}
+
+	if *jsonOutput {
+		problems := []problem{}
+		for _, e := range errs {
+			problems = append(problems, problem{File: e.File, Row: e.Row, Col: e.Col, Message: e.Msg})
+		}
+		out, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		fmt.Println(string(out))
+	} else {
+		for _, e := range errs {
+			fmt.Println(e)
+		}
+	}
+
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"flag"
 	"log"
 
 	"runtime"
 
 	"github.com/zenja/mario/game"
+	"github.com/zenja/mario/graphic"
 )
 
 var G *game.Game
@@ -20,13 +22,37 @@ func must(err error) {
 	}
 }
 
+var (
+	recordFile = flag.String("record", "", "record input to a replay file")
+	replayFile = flag.String("replay", "", "replay input from a replay file instead of keyboard")
+	inputFile  = flag.String("input", "", "load key bindings from this config file")
+	rewind     = flag.Bool("rewind", false, "keep the last seconds of play to rewind by holding rewind key")
+)
+
 func main() {
+	flag.Parse()
+
 	defer quit()
 
 	// this will prevent window not responding
 	runtime.LockOSThread()
 
+	// render to a real window
+	graphic.Init(graphic.NewSDLBackend())
+
 	G = game.NewGame()
+	if len(*inputFile) > 0 {
+		G.UseInputConfig(*inputFile)
+	}
+	if len(*recordFile) > 0 {
+		G.RecordTo(*recordFile)
+	}
+	if len(*replayFile) > 0 {
+		G.ReplayFrom(*replayFile)
+	}
+	if *rewind {
+		G.EnableRewind()
+	}
 	G.Init()
 	G.StartGameLoop()
 }
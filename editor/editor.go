@@ -0,0 +1,459 @@
+// Package editor is an in-game level editor working on level specs
+//
+// Controls:
+//   - arrow keys or WASD: move camera
+//   - left mouse button: paint the selected tile or decoration, right mouse button: erase
+//   - mouse wheel, or clicking the palette at the bottom: select what to paint
+//   - Tab: switch between tiles and decorations
+//   - Ctrl+Z: undo, Ctrl+Y or Ctrl+Shift+Z: redo
+//   - Ctrl+S: save to level file
+//   - P: play-test from the tile under mouse
+package editor
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/math_utils"
+	"github.com/zenja/mario/vector"
+)
+
+type layer int
+
+const (
+	layer_tiles layer = iota
+	layer_decorations
+	num_layers
+)
+
+const (
+	// character of nothing in both level and decoration definitions
+	empty_glyph = '.'
+
+	// camera speed in pixels per frame
+	cam_speed = 20
+
+	// palette strip at the bottom of screen
+	palette_top     = graphic.SCREEN_HEIGHT - graphic.TILE_SIZE
+	palette_visible = graphic.SCREEN_WIDTH / graphic.TILE_SIZE
+
+	// how long a status message is shown
+	status_ms = 3000
+)
+
+type Editor struct {
+	// the spec being edited, a copy of the one given
+	spec *level.LevelSpec
+
+	// spec built for drawing, rebuilt after changes
+	preview      *level.Level
+	previewDirty bool
+	clock        clock.Clock
+
+	camPos vector.Pos
+
+	layer    layer
+	palettes [num_layers]*palette
+
+	// mouse position in screen
+	mousePos vector.Pos
+
+	// mouse button painting the current stroke, 0 if not painting
+	paintButton uint8
+	// last tile painted in the current stroke
+	lastPainted vector.TileID
+	// changes of the current stroke, pushed to history when the stroke ends
+	stroke edit
+
+	history history
+
+	// if there are changes not saved to level file
+	unsaved bool
+
+	status      string
+	statusTicks uint32
+
+	// set when play-test is requested
+	playTestPos *vector.Pos
+}
+
+func NewEditor(spec *level.LevelSpec, clk clock.Clock, camPos vector.Pos) *Editor {
+	ed := &Editor{
+		spec:   spec.Clone(),
+		clock:  clk,
+		camPos: camPos,
+	}
+	ed.palettes[layer_tiles] = newTilePalette(ed.spec)
+	ed.palettes[layer_decorations] = newDecorationPalette()
+
+	preview, err := level.BuildLevel(ed.spec, clk)
+	if err != nil {
+		// the spec has been built by the game, it should never happen
+		log.Fatal(err)
+	}
+	ed.preview = preview
+
+	return ed
+}
+
+// Spec returns the edited spec
+func (ed *Editor) Spec() *level.LevelSpec {
+	return ed.spec
+}
+
+// SelectedGlyph returns the character painted with left mouse button in current layer
+func (ed *Editor) SelectedGlyph() byte {
+	return ed.currentPalette().selectedEntry().glyph
+}
+
+// PlayTestRequested tells if play-test is requested and where hero should start, in level
+func (ed *Editor) PlayTestRequested() (vector.Pos, bool) {
+	if ed.playTestPos == nil {
+		return vector.Pos{}, false
+	}
+	return *ed.playTestPos, true
+}
+
+// HandleEvent handles mouse and keyboard events from SDL
+func (ed *Editor) HandleEvent(e sdl.Event) {
+	switch t := e.(type) {
+	case *sdl.MouseMotionEvent:
+		ed.mousePos = vector.Pos{t.X, t.Y}
+		if ed.paintButton != 0 {
+			ed.paintTo(ed.mouseTile())
+		}
+
+	case *sdl.MouseButtonEvent:
+		ed.mousePos = vector.Pos{t.X, t.Y}
+		if t.State == sdl.RELEASED {
+			if t.Button == ed.paintButton {
+				ed.endStroke()
+			}
+			return
+		}
+		if t.Button != sdl.BUTTON_LEFT && t.Button != sdl.BUTTON_RIGHT {
+			return
+		}
+		if ed.mousePos.Y >= palette_top {
+			if i, ok := ed.currentPalette().entryAt(ed.mousePos.X); ok {
+				ed.currentPalette().selected = i
+			}
+			return
+		}
+		ed.endStroke()
+		ed.paintButton = t.Button
+		ed.lastPainted = ed.mouseTile()
+		ed.paintAt(ed.lastPainted)
+
+	case *sdl.MouseWheelEvent:
+		if t.Y > 0 {
+			ed.currentPalette().move(-1)
+		} else if t.Y < 0 {
+			ed.currentPalette().move(1)
+		}
+
+	case *sdl.KeyDownEvent:
+		ctrl := t.Keysym.Mod&sdl.KMOD_CTRL != 0
+		shift := t.Keysym.Mod&sdl.KMOD_SHIFT != 0
+		switch {
+		case t.Keysym.Scancode == sdl.SCANCODE_TAB && t.Repeat == 0:
+			ed.endStroke()
+			ed.layer = (ed.layer + 1) % num_layers
+		case t.Keysym.Scancode == sdl.SCANCODE_Z && ctrl && !shift:
+			ed.undo()
+		case (t.Keysym.Scancode == sdl.SCANCODE_Z && ctrl && shift) || (t.Keysym.Scancode == sdl.SCANCODE_Y && ctrl):
+			ed.redo()
+		case t.Keysym.Scancode == sdl.SCANCODE_S && ctrl && t.Repeat == 0:
+			ed.save()
+		case t.Keysym.Scancode == sdl.SCANCODE_P && !ctrl && t.Repeat == 0:
+			ed.requestPlayTest()
+		}
+	}
+}
+
+// Update moves camera with keyboard and rebuilds the preview if needed, it should be called once per frame
+func (ed *Editor) Update() {
+	keyState := sdl.GetKeyboardState()
+	pressed := func(codes ...sdl.Scancode) bool {
+		for _, code := range codes {
+			if keyState[code] != 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// WASD are also used with Ctrl for shortcuts
+	if !pressed(sdl.SCANCODE_LCTRL) {
+		if pressed(sdl.SCANCODE_LEFT, sdl.SCANCODE_A) {
+			ed.camPos.X -= cam_speed
+		}
+		if pressed(sdl.SCANCODE_RIGHT, sdl.SCANCODE_D) {
+			ed.camPos.X += cam_speed
+		}
+		if pressed(sdl.SCANCODE_UP, sdl.SCANCODE_W) {
+			ed.camPos.Y -= cam_speed
+		}
+		if pressed(sdl.SCANCODE_DOWN, sdl.SCANCODE_S) {
+			ed.camPos.Y += cam_speed
+		}
+	}
+	ed.clampCamPos()
+
+	if ed.previewDirty {
+		ed.rebuildPreview()
+	}
+}
+
+// Draw draws the level being edited, palette and status, everything except showing the screen
+func (ed *Editor) Draw() {
+	graphic.ClearScreenWithColor(ed.preview.BGColor)
+	ed.preview.Draw(ed.camPos)
+	ed.drawEntries()
+
+	// tile under mouse
+	if ed.mousePos.Y < palette_top {
+		tid := ed.mouseTile()
+		rect := sdl.Rect{tid.X * graphic.TILE_SIZE, tid.Y * graphic.TILE_SIZE, graphic.TILE_SIZE, graphic.TILE_SIZE}
+		graphic.DrawRect(rect, ed.camPos)
+	}
+
+	ed.currentPalette().draw()
+
+	white := sdl.Color{255, 255, 255, 255}
+	layerName := "tiles"
+	if ed.layer == layer_decorations {
+		layerName = "decorations"
+	}
+	title := fmt.Sprintf("EDITOR %s [%s]", ed.spec.Name, layerName)
+	if ed.unsaved {
+		title += " *"
+	}
+	graphic.DrawText(title, vector.Pos{10, 10}, white)
+	if len(ed.status) > 0 && sdl.GetTicks()-ed.statusTicks < status_ms {
+		graphic.DrawText(ed.status, vector.Pos{10, 40}, white)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (ed *Editor) currentPalette() *palette {
+	return ed.palettes[ed.layer]
+}
+
+func (ed *Editor) arr(l layer) [][]byte {
+	if l == layer_decorations {
+		return ed.spec.DecArr
+	}
+	return ed.spec.LevelArr
+}
+
+func (ed *Editor) mouseTile() vector.TileID {
+	return vector.TileID{
+		(ed.mousePos.X + ed.camPos.X) / graphic.TILE_SIZE,
+		(ed.mousePos.Y + ed.camPos.Y) / graphic.TILE_SIZE,
+	}
+}
+
+func (ed *Editor) setStatus(format string, args ...interface{}) {
+	ed.status = fmt.Sprintf(format, args...)
+	ed.statusTicks = sdl.GetTicks()
+}
+
+// clampCamPos keeps camera inside level, the bottom row can be moved above palette
+func (ed *Editor) clampCamPos() {
+	maxX := ed.preview.GetLevelWidth() - graphic.SCREEN_WIDTH
+	maxY := ed.preview.GetLevelHeight() - palette_top
+	ed.camPos.X = math_utils.Max(0, math_utils.Min(ed.camPos.X, maxX))
+	ed.camPos.Y = math_utils.Max(0, math_utils.Min(ed.camPos.Y, maxY))
+}
+
+// paintTo paints all tiles from the last painted one to the given one, so that fast mouse moves leave no gaps
+func (ed *Editor) paintTo(tid vector.TileID) {
+	if tid == ed.lastPainted {
+		return
+	}
+	dx := tid.X - ed.lastPainted.X
+	dy := tid.Y - ed.lastPainted.Y
+	steps := math_utils.Max(math_utils.Abs(dx), math_utils.Abs(dy))
+	for i := int32(1); i <= steps; i++ {
+		ed.paintAt(vector.TileID{
+			ed.lastPainted.X + dx*i/steps,
+			ed.lastPainted.Y + dy*i/steps,
+		})
+	}
+	ed.lastPainted = tid
+}
+
+// paintAt paints the selected entry at a tile, or erases it if painting with right button
+func (ed *Editor) paintAt(tid vector.TileID) {
+	x, y := int(tid.X), int(tid.Y)
+	arr := ed.arr(ed.layer)
+	if y < 0 || y >= len(arr) || x < 0 || x >= len(arr[y]) {
+		return
+	}
+
+	glyph := byte(empty_glyph)
+	if ed.paintButton == sdl.BUTTON_LEFT {
+		glyph = ed.SelectedGlyph()
+	}
+
+	if ed.layer == layer_decorations {
+		ed.setCell(layer_decorations, x, y, glyph)
+		return
+	}
+
+	if err := ed.checkTileChange(x, y, glyph); err != nil {
+		ed.setStatus("%s", err)
+		return
+	}
+	// there is only one hero, painting it somewhere else moves it
+	if ed.isHero(glyph) {
+		if hx, hy, ok := ed.findHero(); ok {
+			ed.setCell(layer_tiles, hx, hy, empty_glyph)
+		}
+	}
+	ed.setCell(layer_tiles, x, y, glyph)
+}
+
+// checkTileChange checks if a tile can be changed, so that the spec can always be built
+func (ed *Editor) checkTileChange(x, y int, glyph byte) error {
+	old := ed.spec.LevelArr[y][x]
+	if old == glyph {
+		return nil
+	}
+	if ed.isHero(old) {
+		return fmt.Errorf("hero can't be erased, paint it somewhere else to move it")
+	}
+	if t, ok := ed.tileType(old); ok && t.JumpsLevel {
+		return fmt.Errorf("level pipes can only be changed in level file")
+	}
+	// pipes of other levels come out of entries by name
+	if t, ok := ed.tileType(old); ok && t.Name == level.EntryTileType {
+		return fmt.Errorf("entries can only be changed in level file")
+	}
+	return nil
+}
+
+func (ed *Editor) setCell(l layer, x, y int, glyph byte) {
+	arr := ed.arr(l)
+	if arr[y][x] == glyph {
+		return
+	}
+	ed.stroke = append(ed.stroke, cellChange{layer: l, x: x, y: y, old: arr[y][x], new: glyph})
+	arr[y][x] = glyph
+	ed.previewDirty = true
+	ed.unsaved = true
+}
+
+func (ed *Editor) endStroke() {
+	ed.history.push(ed.stroke)
+	ed.stroke = nil
+	ed.paintButton = 0
+}
+
+func (ed *Editor) undo() {
+	ed.endStroke()
+	e, ok := ed.history.undo()
+	if !ok {
+		ed.setStatus("nothing to undo")
+		return
+	}
+	for i := len(e) - 1; i >= 0; i-- {
+		ed.arr(e[i].layer)[e[i].y][e[i].x] = e[i].old
+	}
+	ed.previewDirty = true
+	ed.unsaved = true
+}
+
+func (ed *Editor) redo() {
+	ed.endStroke()
+	e, ok := ed.history.redo()
+	if !ok {
+		ed.setStatus("nothing to redo")
+		return
+	}
+	for _, c := range e {
+		ed.arr(c.layer)[c.y][c.x] = c.new
+	}
+	ed.previewDirty = true
+	ed.unsaved = true
+}
+
+func (ed *Editor) save() {
+	ed.endStroke()
+	if err := level.SaveLevelDefs(ed.spec); err != nil {
+		ed.setStatus("%s", err)
+		return
+	}
+	ed.unsaved = false
+	ed.setStatus("saved to %s", ed.spec.Filename)
+}
+
+func (ed *Editor) requestPlayTest() {
+	ed.endStroke()
+	if ed.mousePos.Y >= palette_top {
+		return
+	}
+	tid := ed.mouseTile()
+	ed.playTestPos = &vector.Pos{tid.X * graphic.TILE_SIZE, tid.Y * graphic.TILE_SIZE}
+}
+
+func (ed *Editor) rebuildPreview() {
+	preview, err := level.BuildLevel(ed.spec, ed.clock)
+	if err != nil {
+		// changes are checked before made, but never leave the spec broken
+		ed.setStatus("%s", err)
+		ed.undo()
+		ed.history.redos = nil
+		return
+	}
+	ed.preview = preview
+	ed.previewDirty = false
+}
+
+func (ed *Editor) tileType(c byte) (level.TileType, bool) {
+	td, ok := ed.spec.TileOf(c)
+	if !ok {
+		return level.TileType{}, false
+	}
+	return level.GetTileType(td.Type)
+}
+
+// drawEntries marks entries with their names, they are invisible in game
+func (ed *Editor) drawEntries() {
+	white := sdl.Color{255, 255, 255, 255}
+	for y, row := range ed.spec.LevelArr {
+		for x := range row {
+			td, ok := ed.spec.TileAt(x, y)
+			if !ok || td.Type != level.EntryTileType {
+				continue
+			}
+			rect := sdl.Rect{int32(x) * graphic.TILE_SIZE, int32(y) * graphic.TILE_SIZE, graphic.TILE_SIZE, graphic.TILE_SIZE}
+			graphic.DrawRect(rect, ed.camPos)
+			graphic.DrawText(td.StringParam("name"), vector.Pos{rect.X - ed.camPos.X + 5, rect.Y - ed.camPos.Y + 10}, white)
+		}
+	}
+}
+
+func (ed *Editor) isHero(c byte) bool {
+	t, ok := ed.tileType(c)
+	return ok && t.Name == level.HeroTileType
+}
+
+func (ed *Editor) findHero() (int, int, bool) {
+	for y, row := range ed.spec.LevelArr {
+		for x, c := range row {
+			if ed.isHero(c) {
+				return x, y, true
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,188 @@
+package editor_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/editor"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/vector"
+)
+
+func TestMain(m *testing.M) {
+	// asset paths are relative to the repo root
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+
+	graphic.Init(graphic.NewNullBackend())
+
+	os.Exit(m.Run())
+}
+
+func TestPaintUndoRedo(t *testing.T) {
+	spec := newTestSpec(
+		".H....",
+		"......",
+		"BBBBBB",
+	)
+	ed := editor.NewEditor(spec, clock.NewManualClock(1), vector.Pos{})
+
+	// erase a stroke of bricks, moving fast enough to skip tiles between events
+	press(ed, sdl.BUTTON_RIGHT, 0, 2)
+	move(ed, 4, 2)
+	release(ed, sdl.BUTTON_RIGHT, 4, 2)
+	expectRows(t, ed.Spec().LevelArr, ".H....", "......", ".....B")
+
+	// the original spec is not changed
+	expectRows(t, spec.LevelArr, ".H....", "......", "BBBBBB")
+
+	key(ed, sdl.SCANCODE_Z, sdl.KMOD_CTRL)
+	expectRows(t, ed.Spec().LevelArr, ".H....", "......", "BBBBBB")
+
+	key(ed, sdl.SCANCODE_Y, sdl.KMOD_CTRL)
+	expectRows(t, ed.Spec().LevelArr, ".H....", "......", ".....B")
+}
+
+func TestHeroIsMovedNotErased(t *testing.T) {
+	spec := newTestSpec(
+		".H.{}.",
+		"BBBBBB",
+	)
+	spec.NextLevelNames = []string{"next"}
+	ed := editor.NewEditor(spec, clock.NewManualClock(1), vector.Pos{})
+
+	// neither the hero nor level pipes can be erased
+	press(ed, sdl.BUTTON_RIGHT, 1, 0)
+	move(ed, 3, 0)
+	release(ed, sdl.BUTTON_RIGHT, 3, 0)
+	expectRows(t, ed.Spec().LevelArr, ".H.{}.", "BBBBBB")
+
+	// select hero from palette and paint it elsewhere
+	for i := 0; i < 100 && ed.SelectedGlyph() != 'H'; i++ {
+		ed.HandleEvent(&sdl.MouseWheelEvent{Y: -1})
+	}
+	press(ed, sdl.BUTTON_LEFT, 5, 0)
+	release(ed, sdl.BUTTON_LEFT, 5, 0)
+	expectRows(t, ed.Spec().LevelArr, "...{}H", "BBBBBB")
+
+	// moving is undone at once
+	key(ed, sdl.SCANCODE_Z, sdl.KMOD_CTRL)
+	expectRows(t, ed.Spec().LevelArr, ".H.{}.", "BBBBBB")
+}
+
+func TestEntriesAreKept(t *testing.T) {
+	spec := newTestSpec(
+		".H.a..",
+		"BBBBBB",
+	)
+	spec.Legend = map[byte]level.TileDef{
+		'a': {Type: level.EntryTileType, Params: map[string]interface{}{"name": "from-secret"}},
+	}
+	ed := editor.NewEditor(spec, clock.NewManualClock(1), vector.Pos{})
+
+	// pipes of other levels come out of the entry, it can be neither erased nor painted over
+	press(ed, sdl.BUTTON_RIGHT, 3, 0)
+	release(ed, sdl.BUTTON_RIGHT, 3, 0)
+	expectRows(t, ed.Spec().LevelArr, ".H.a..", "BBBBBB")
+
+	press(ed, sdl.BUTTON_LEFT, 3, 0)
+	release(ed, sdl.BUTTON_LEFT, 3, 0)
+	expectRows(t, ed.Spec().LevelArr, ".H.a..", "BBBBBB")
+
+	// and it is not in the palette to be painted elsewhere
+	for i := 0; i < 100; i++ {
+		if ed.SelectedGlyph() == 'a' {
+			t.Fatal("expected entries not in the palette")
+		}
+		ed.HandleEvent(&sdl.MouseWheelEvent{Y: -1})
+	}
+}
+
+func TestPaintDecorations(t *testing.T) {
+	spec := newTestSpec(
+		".H..",
+		"BBBB",
+	)
+	ed := editor.NewEditor(spec, clock.NewManualClock(1), vector.Pos{})
+
+	key(ed, sdl.SCANCODE_TAB, 0)
+	press(ed, sdl.BUTTON_LEFT, 2, 0)
+	release(ed, sdl.BUTTON_LEFT, 2, 0)
+
+	expectRows(t, ed.Spec().LevelArr, ".H..", "BBBB")
+	if c := ed.Spec().DecArr[0][2]; c != level.DecorationGlyphs["grass"] {
+		t.Errorf("expected grass decoration painted but was '%c'", c)
+	}
+}
+
+func TestPlayTestFromMouse(t *testing.T) {
+	ed := editor.NewEditor(newTestSpec(".H..", "BBBB"), clock.NewManualClock(1), vector.Pos{})
+	if _, ok := ed.PlayTestRequested(); ok {
+		t.Fatal("play-test should not be requested yet")
+	}
+
+	move(ed, 3, 0)
+	key(ed, sdl.SCANCODE_P, 0)
+	pos, ok := ed.PlayTestRequested()
+	if !ok || pos != (vector.Pos{3 * graphic.TILE_SIZE, 0}) {
+		t.Errorf("expected play-test from tile 3, 0 but was %v %v", pos, ok)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func newTestSpec(rows ...string) *level.LevelSpec {
+	var levelArr, decArr [][]byte
+	for _, r := range rows {
+		levelArr = append(levelArr, []byte(r))
+		decArr = append(decArr, bytes.Repeat([]byte("."), len(r)))
+	}
+	return &level.LevelSpec{
+		Name:       "test",
+		BgFilename: "assets/bg-0.png",
+		BgColor:    sdl.Color{0, 0, 0, 255},
+		LevelArr:   levelArr,
+		DecArr:     decArr,
+	}
+}
+
+// tile center in screen, camera is at level origin in tests
+func tileCenter(x, y int32) (int32, int32) {
+	return x*graphic.TILE_SIZE + graphic.TILE_SIZE/2, y*graphic.TILE_SIZE + graphic.TILE_SIZE/2
+}
+
+func press(ed *editor.Editor, button uint8, x, y int32) {
+	sx, sy := tileCenter(x, y)
+	ed.HandleEvent(&sdl.MouseButtonEvent{Button: button, State: sdl.PRESSED, X: sx, Y: sy})
+}
+
+func release(ed *editor.Editor, button uint8, x, y int32) {
+	sx, sy := tileCenter(x, y)
+	ed.HandleEvent(&sdl.MouseButtonEvent{Button: button, State: sdl.RELEASED, X: sx, Y: sy})
+}
+
+func move(ed *editor.Editor, x, y int32) {
+	sx, sy := tileCenter(x, y)
+	ed.HandleEvent(&sdl.MouseMotionEvent{X: sx, Y: sy})
+}
+
+func key(ed *editor.Editor, code sdl.Scancode, mod uint16) {
+	ed.HandleEvent(&sdl.KeyDownEvent{Keysym: sdl.Keysym{Scancode: code, Mod: mod}})
+}
+
+func expectRows(t *testing.T, arr [][]byte, rows ...string) {
+	t.Helper()
+	for y, want := range rows {
+		if string(arr[y]) != want {
+			t.Errorf("expected row %d to be %q but was %q", y, want, arr[y])
+		}
+	}
+}
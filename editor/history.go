@@ -0,0 +1,48 @@
+package editor
+
+// cellChange is a change of one character in level or decoration definition
+type cellChange struct {
+	layer    layer
+	x, y     int
+	old, new byte
+}
+
+// edit is what is undone or redone at once, e.g. all tiles painted by one stroke of mouse
+type edit []cellChange
+
+// history keeps edits for undo and redo
+type history struct {
+	undos []edit
+	redos []edit
+}
+
+// push adds a new edit, which makes undone edits impossible to redo
+func (h *history) push(e edit) {
+	if len(e) == 0 {
+		return
+	}
+	h.undos = append(h.undos, e)
+	h.redos = nil
+}
+
+// undo returns the last edit to undo, false if nothing to undo
+func (h *history) undo() (edit, bool) {
+	if len(h.undos) == 0 {
+		return nil, false
+	}
+	e := h.undos[len(h.undos)-1]
+	h.undos = h.undos[:len(h.undos)-1]
+	h.redos = append(h.redos, e)
+	return e, true
+}
+
+// redo returns the last undone edit to do again, false if nothing to redo
+func (h *history) redo() (edit, bool) {
+	if len(h.redos) == 0 {
+		return nil, false
+	}
+	e := h.redos[len(h.redos)-1]
+	h.redos = h.redos[:len(h.redos)-1]
+	h.undos = append(h.undos, e)
+	return e, true
+}
@@ -0,0 +1,143 @@
+package editor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/vector"
+)
+
+// palette is what can be painted in a layer, shown in a strip at the bottom of screen
+type palette struct {
+	entries  []paletteEntry
+	selected int
+}
+
+type paletteEntry struct {
+	glyph byte
+	name  string
+
+	// what the entry looks like, placed at TileID{index, 0}; nil if there is nothing to draw
+	preview level.Object
+}
+
+// newTilePalette lists all tiles which can be painted in the level
+// Level pipes and entries are not listed since they can only be set in level file
+func newTilePalette(spec *level.LevelSpec) *palette {
+	p := &palette{}
+	add := func(c byte, name string) {
+		p.entries = append(p.entries, paletteEntry{
+			glyph:   c,
+			name:    name,
+			preview: level.NewPreviewObject(spec, c, vector.TileID{int32(len(p.entries)), 0}),
+		})
+	}
+
+	for _, name := range level.TileTypeNames() {
+		t, _ := level.GetTileType(name)
+		if t.JumpsLevel || t.Name == level.EntryTileType || t.Glyph == empty_glyph {
+			continue
+		}
+		// the character may mean something else in this level
+		if td, ok := spec.TileOf(t.Glyph); !ok || td.Type != name {
+			continue
+		}
+		add(t.Glyph, name)
+	}
+
+	// characters of legend
+	var glyphs []int
+	for c := range spec.Legend {
+		glyphs = append(glyphs, int(c))
+	}
+	sort.Ints(glyphs)
+	for _, c := range glyphs {
+		td := spec.Legend[byte(c)]
+		if t, _ := level.GetTileType(td.Type); t.JumpsLevel || t.Name == level.EntryTileType {
+			continue
+		}
+		name := td.Type
+		if len(td.Params) > 0 {
+			name = fmt.Sprintf("%s %v", td.Type, td.Params)
+		}
+		add(byte(c), name)
+	}
+
+	return p
+}
+
+// newDecorationPalette lists all decorations
+func newDecorationPalette() *palette {
+	var names []string
+	for name := range level.DecorationGlyphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p := &palette{}
+	for _, name := range names {
+		p.entries = append(p.entries, paletteEntry{glyph: level.DecorationGlyphs[name], name: name})
+	}
+	return p
+}
+
+func (p *palette) selectedEntry() paletteEntry {
+	return p.entries[p.selected]
+}
+
+// move moves selection by delta entries, wrapping around
+func (p *palette) move(delta int) {
+	n := len(p.entries)
+	p.selected = ((p.selected+delta)%n + n) % n
+}
+
+// firstVisible returns the first entry shown in the strip, so that the selected one is always visible
+func (p *palette) firstVisible() int {
+	first := p.selected - palette_visible/2
+	if first > len(p.entries)-palette_visible {
+		first = len(p.entries) - palette_visible
+	}
+	if first < 0 {
+		first = 0
+	}
+	return first
+}
+
+// entryAt returns the index of entry shown at screen x, false if none
+func (p *palette) entryAt(screenX int32) (int, bool) {
+	i := p.firstVisible() + int(screenX/graphic.TILE_SIZE)
+	if i < 0 || i >= len(p.entries) {
+		return 0, false
+	}
+	return i, true
+}
+
+func (p *palette) draw() {
+	first := p.firstVisible()
+	top := int32(palette_top)
+
+	// previews are placed in a row from level origin, move the camera so that they show in the strip
+	camPos := vector.Pos{int32(first) * graphic.TILE_SIZE, -top}
+	screenCam := vector.Pos{}
+	white := sdl.Color{255, 255, 255, 255}
+
+	for i := first; i < len(p.entries) && i < first+palette_visible; i++ {
+		e := p.entries[i]
+		cell := sdl.Rect{int32(i-first) * graphic.TILE_SIZE, top, graphic.TILE_SIZE, graphic.TILE_SIZE}
+		if e.preview != nil {
+			e.preview.Draw(camPos)
+		} else {
+			graphic.DrawText(string(e.glyph), vector.Pos{cell.X + 15, cell.Y + 10}, white)
+		}
+		graphic.DrawRect(cell, screenCam)
+		if i == p.selected {
+			graphic.DrawRect(sdl.Rect{cell.X + 3, cell.Y + 3, cell.W - 6, cell.H - 6}, screenCam)
+		}
+	}
+
+	selected := p.selectedEntry()
+	graphic.DrawText(fmt.Sprintf("[%c] %s", selected.glyph, selected.name), vector.Pos{10, top - 30}, white)
+}
@@ -16,4 +16,62 @@ const (
 	EVENT_KEYDOWN_F3
 	EVENT_KEYDOWN_F4
 	EVENT_KEYDOWN_F5
+	EVENT_KEYDOWN_F6
+
+	// pauses the game, or goes back in menus
+	// it comes after debug events so that events recorded in replay files keep their values
+	EVENT_KEYDOWN_PAUSE
+
+	// for debug use, saves and loads a snapshot of current level
+	EVENT_KEYDOWN_F7
+	EVENT_KEYDOWN_F8
+
+	// held to rewind the last seconds of play
+	EVENT_KEYDOWN_REWIND
 )
+
+// names of events, used in config files
+var eventNames = map[Event]string{
+	EVENT_KEYDOWN_LEFT:  "left",
+	EVENT_KEYDOWN_RIGHT: "right",
+	EVENT_KEYDOWN_UP:    "up",
+	EVENT_KEYDOWN_DOWN:  "down",
+	EVENT_KEYDOWN_SPACE: "jump",
+	EVENT_KEYDOWN_F:     "fire",
+
+	EVENT_KEYDOWN_F1: "debug-restart",
+	EVENT_KEYDOWN_F2: "debug-upgrade",
+	EVENT_KEYDOWN_F3: "debug-downgrade",
+	EVENT_KEYDOWN_F4: "debug-fade-in",
+	EVENT_KEYDOWN_F5: "debug-switch-level",
+	EVENT_KEYDOWN_F6: "debug-editor",
+
+	EVENT_KEYDOWN_PAUSE: "pause",
+
+	EVENT_KEYDOWN_F7: "debug-quick-save",
+	EVENT_KEYDOWN_F8: "debug-quick-load",
+
+	EVENT_KEYDOWN_REWIND: "rewind",
+}
+
+func (e Event) String() string {
+	if name, ok := eventNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// IsDebug tells if the event is only for debug use
+func (e Event) IsDebug() bool {
+	return e >= EVENT_KEYDOWN_F1 && e <= EVENT_KEYDOWN_F6 || e == EVENT_KEYDOWN_F7 || e == EVENT_KEYDOWN_F8
+}
+
+// ParseEvent returns the event of the given name
+func ParseEvent(name string) (Event, bool) {
+	for e, n := range eventNames {
+		if n == name {
+			return e, true
+		}
+	}
+	return 0, false
+}
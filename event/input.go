@@ -0,0 +1,67 @@
+package event
+
+import "golang.org/x/tools/container/intsets"
+
+// Input is the state of events in one simulation step
+type Input struct {
+	held     intsets.Sparse
+	pressed  intsets.Sparse
+	released intsets.Sparse
+
+	// how many steps an event has been held, including current step
+	heldSteps map[Event]int
+}
+
+// IsHeld tells if the event is down in current step
+func (in *Input) IsHeld(e Event) bool {
+	return in.held.Has(int(e))
+}
+
+// IsPressed tells if the event is down in current step but was not in last step
+func (in *Input) IsPressed(e Event) bool {
+	return in.pressed.Has(int(e))
+}
+
+// IsReleased tells if the event was down in last step but is not in current step
+func (in *Input) IsReleased(e Event) bool {
+	return in.released.Has(int(e))
+}
+
+// HeldSteps returns how many steps the event has been held, 0 if it is not held
+func (in *Input) HeldSteps(e Event) int {
+	return in.heldSteps[e]
+}
+
+// Held returns the set of held events, which is all an Input needs to be reproduced by a Tracker
+func (in *Input) Held() *intsets.Sparse {
+	return &in.held
+}
+
+// Tracker turns the held events of each step into an Input with edges
+type Tracker struct {
+	last *Input
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{last: &Input{}}
+}
+
+// Next returns the Input of next step; held can be nil, which means nothing is held
+func (t *Tracker) Next(held *intsets.Sparse) *Input {
+	in := &Input{heldSteps: make(map[Event]int)}
+	if held != nil {
+		in.held.Copy(held)
+	}
+	in.pressed.Difference(&in.held, &t.last.held)
+	in.released.Difference(&t.last.held, &in.held)
+	for _, e := range in.held.AppendTo(nil) {
+		in.heldSteps[Event(e)] = t.last.heldSteps[Event(e)] + 1
+	}
+	t.last = in
+	return in
+}
+
+// Reset forgets the last step, so that held events become pressed again
+func (t *Tracker) Reset() {
+	t.last = &Input{}
+}
@@ -0,0 +1,40 @@
+package event_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/event"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestTrackerEdges(t *testing.T) {
+	var jump intsets.Sparse
+	jump.Insert(int(event.EVENT_KEYDOWN_SPACE))
+
+	tracker := event.NewTracker()
+	steps := []struct {
+		held      *intsets.Sparse
+		pressed   bool
+		released  bool
+		heldSteps int
+	}{
+		{nil, false, false, 0},
+		{&jump, true, false, 1},
+		{&jump, false, false, 2},
+		{&jump, false, false, 3},
+		{nil, false, true, 0},
+		{&jump, true, false, 1},
+	}
+	for i, s := range steps {
+		in := tracker.Next(s.held)
+		if got := in.IsPressed(event.EVENT_KEYDOWN_SPACE); got != s.pressed {
+			t.Errorf("step %d: expected pressed %t but was %t", i, s.pressed, got)
+		}
+		if got := in.IsReleased(event.EVENT_KEYDOWN_SPACE); got != s.released {
+			t.Errorf("step %d: expected released %t but was %t", i, s.released, got)
+		}
+		if got := in.HeldSteps(event.EVENT_KEYDOWN_SPACE); got != s.heldSteps {
+			t.Errorf("step %d: expected held steps %d but was %d", i, s.heldSteps, got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package game
+
+import (
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/level"
+	"golang.org/x/tools/container/intsets"
+)
+
+// testNow is the real time read by clocks of test games, moved by Step
+var testNow uint32
+
+// NewTestGame makes a game playing a level of specs, without window, input devices or save store
+func NewTestGame(specs map[string]*level.LevelSpec, levelName string) *Game {
+	game := NewGame()
+	game.clock = clock.NewGameClock(func() uint32 { return testNow })
+	game.levelSpecs = specs
+	game.startGame(levelName)
+	return game
+}
+
+// Step runs one simulation step with events held down, the same way as the game loop does
+func (game *Game) Step(events *intsets.Sparse) {
+	testNow += level.SIMULATION_STEP_MS
+	game.clock.Tick()
+	if !game.clock.Step(level.SIMULATION_STEP_MS) {
+		return
+	}
+	input := game.inputTracker.Next(events)
+	state := game.state()
+	state.HandleEvents(input)
+	if game.state() == state {
+		state.Update(input)
+	}
+}
+
+// SwitchLevel switches level as if hero went into a level pipe to target
+func (game *Game) SwitchLevel(target string) {
+	game.switchLevel(target)
+}
+
+func (game *Game) CurrentLevel() *level.Level {
+	return game.currentLevel
+}
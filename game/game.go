@@ -1,22 +1,28 @@
 package game
 
 import (
-	"io/ioutil"
 	"log"
+	"os"
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/editor"
 	"github.com/zenja/mario/event"
 	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/input"
 	"github.com/zenja/mario/level"
 	"github.com/zenja/mario/overlay"
+	"github.com/zenja/mario/replay"
+	"github.com/zenja/mario/save"
 	"github.com/zenja/mario/vector"
 	"golang.org/x/tools/container/intsets"
 )
 
 const (
-	first_level_name = "level-0"
-	level_dir        = "assets/levels"
+	first_level_name  = "level-0"
+	level_dir         = "assets/levels"
+	input_config_file = "assets/input.toml"
 )
 
 type Game struct {
@@ -24,8 +30,52 @@ type Game struct {
 	camPos       vector.Pos
 	levelSpecs   map[string]*level.LevelSpec
 	currentLevel *level.Level
-	running      bool
-	overlays     []overlay.Overlay
+
+	// persistent levels left by hero by name, entered again as they were, see LevelSpec.Persistent
+	levelCache map[string]*level.Level
+	running    bool
+	overlays   []overlay.Overlay
+
+	// game time, all level logic reads ticks from it
+	// it moves in fixed steps of level.SIMULATION_STEP_MS, decoupled from rendering
+	clock *clock.GameClock
+
+	// if not empty, record input to / replay input from these files
+	recordFile string
+	replayFile string
+	recorder   *replay.Recorder
+	player     *replay.Player
+
+	// maps keyboard keys and gamepad buttons to events
+	inputConfigFile string
+	inputMapping    *input.Mapping
+	gamepads        *input.Gamepads
+
+	// finds out pressed/released events step by step
+	inputTracker *event.Tracker
+
+	// level editor, nil if not editing
+	editor *editor.Editor
+
+	// snapshot of a level saved by quick save for debug, nil if never saved
+	quickSave *level.Snapshot
+
+	// if true, levels record their last steps to be rewound, see level.Level.EnableRewind
+	rewind bool
+
+	// stack of game states, the top one is running, see gameState
+	states []gameState
+
+	// set when steps left in current frame should not run, e.g. after switching level or state
+	frameDone bool
+
+	options options
+
+	// campaign progress of the profile slot being played, saved on level transitions
+	// saveStore is nil if there is nowhere to save
+	saveStore *save.Store
+	saveSlot  int
+	progress  *save.Progress
 }
 
 func NewGame() *Game {
@@ -33,11 +83,20 @@ func NewGame() *Game {
 	var overlays []overlay.Overlay
 	overlays = append(overlays, &overlay.FPSOverlay{})
 	overlays = append(overlays, &overlay.CoinsOverlay{})
+	overlays = append(overlays, &overlay.ScoreOverlay{})
 	overlays = append(overlays, &overlay.HeroLiveOverlay{})
+	overlays = append(overlays, &overlay.TimerOverlay{})
 
 	return &Game{
-		levelSpecs: make(map[string]*level.LevelSpec),
-		overlays:   overlays,
+		levelSpecs:      make(map[string]*level.LevelSpec),
+		levelCache:      make(map[string]*level.Level),
+		overlays:        overlays,
+		clock:           clock.NewGameClock(sdl.GetTicks),
+		inputConfigFile: input_config_file,
+		inputTracker:    event.NewTracker(),
+		options:         defaultOptions,
+		saveSlot:        1,
+		progress:        save.NewProgress(),
 	}
 }
 
@@ -45,11 +104,56 @@ func (game *Game) Init() {
 	// init audio system
 	audio.InitAudio()
 
+	game.loadInputMapping()
 	game.loadLevels()
+	game.openSaveStore()
+	if len(game.replayFile) == 0 && len(game.recordFile) == 0 {
+		game.setState(newTitleState(game))
+		return
+	}
+
+	// replays always start playing at once
+	if len(game.replayFile) > 0 {
+		game.startReplay()
+	}
 	game.currentLevel.Init()
+	game.setState(&playingState{game: game})
+	if len(game.recordFile) > 0 {
+		game.startRecording()
+	}
+}
+
+// UseInputConfig makes the game load key bindings from another config file, it has to be called before Init()
+func (game *Game) UseInputConfig(filename string) {
+	game.inputConfigFile = filename
+}
+
+// RecordTo makes the game record all input to a replay file, it has to be called before Init()
+func (game *Game) RecordTo(filename string) {
+	game.recordFile = filename
+}
+
+// ReplayFrom makes the game take input from a replay file instead of keyboard, it has to be called before Init()
+// When the replay is finished, keyboard takes over
+func (game *Game) ReplayFrom(filename string) {
+	game.replayFile = filename
+}
+
+// EnableRewind makes levels rewindable by holding rewind, it has to be called before Init()
+func (game *Game) EnableRewind() {
+	game.rewind = true
 }
 
 func (game *Game) Quit() {
+	if game.recorder != nil {
+		if err := game.recorder.Close(); err != nil {
+			log.Printf("failed to save replay: %s", err)
+		}
+		game.recorder = nil
+	}
+	if game.gamepads != nil {
+		game.gamepads.CloseAll()
+	}
 	graphic.DestroyAndQuit()
 	audio.Destroy()
 }
@@ -59,36 +163,37 @@ func (game *Game) StartGameLoop() {
 	for game.running {
 		frameStart := sdl.GetTicks()
 
-		events := game.gatherEvents()
-
-		// game event handling
-		game.handleGlobalEvents(events)
-
-		// level event handling
-		game.currentLevel.HandleEvents(events)
-
-		// update current level
-		game.currentLevel.Update(events, sdl.GetTicks())
-
-		// check if need to switch level
-		nextLevel, shouldSwitchLevel := game.currentLevel.GetNextLevel()
-		if shouldSwitchLevel {
-			game.switchLevel(nextLevel)
-			continue
+		liveEvents := game.gatherEvents()
+		if !game.running {
+			break
 		}
 
-		// update camera position
-		game.updateCamPos()
-
-		// start render
-		graphic.ClearScreenWithColor(game.currentLevel.BGColor)
+		// update current level in fixed steps, as many as the real time passed allows
+		// game clock is paused while editing, so no steps are run
+		game.clock.Tick()
+		if game.editor != nil {
+			game.updateEditor(liveEvents)
+		}
+		game.frameDone = false
+		for game.clock.Step(level.SIMULATION_STEP_MS) {
+			input := game.inputTracker.Next(game.stepEvents(liveEvents))
+
+			// the running state handles events and updates, unless the events have switched state
+			state := game.state()
+			state.HandleEvents(input)
+			if game.state() == state {
+				state.Update(input)
+			}
 
-		// render current level
-		game.currentLevel.Draw(game.camPos, sdl.GetTicks())
+			if game.frameDone || !game.running {
+				break
+			}
+		}
 
-		// render overlays
-		for _, ol := range game.overlays {
-			ol.Draw(game.currentLevel, sdl.GetTicks())
+		if game.editor != nil {
+			game.editor.Draw()
+		} else {
+			game.state().Draw()
 		}
 
 		// show screen
@@ -109,49 +214,96 @@ func (game *Game) StartGameLoop() {
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (game *Game) gatherEvents() *intsets.Sparse {
-	var events intsets.Sparse
 	for e := sdl.PollEvent(); e != nil; e = sdl.PollEvent() {
 		switch e.(type) {
 		case *sdl.QuitEvent:
 			game.running = false
 			return nil
+		default:
+			game.gamepads.HandleEvent(e)
+			if game.editor != nil {
+				game.editor.HandleEvent(e)
+			}
 		}
 	}
-	kbState := sdl.GetKeyboardState()
-	if kbState[int(sdl.SCANCODE_LEFT)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_LEFT))
-	}
-	if kbState[int(sdl.SCANCODE_RIGHT)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_RIGHT))
-	}
-	if kbState[int(sdl.SCANCODE_UP)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_UP))
-	}
-	if kbState[int(sdl.SCANCODE_DOWN)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_DOWN))
+	events := game.inputMapping.Events(sdl.GetKeyboardState())
+	events.UnionWith(game.gamepads.Events())
+	return events
+}
+
+func (game *Game) loadInputMapping() {
+	if _, err := os.Stat(game.inputConfigFile); os.IsNotExist(err) {
+		log.Printf("input config %s not found, use default key bindings", game.inputConfigFile)
+		game.inputMapping = input.NewDefaultMapping()
+		game.gamepads = input.NewGamepads(game.inputMapping)
+		return
 	}
-	if kbState[int(sdl.SCANCODE_SPACE)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_SPACE))
+	mapping, err := input.LoadMapping(game.inputConfigFile)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if kbState[int(sdl.SCANCODE_F)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F))
+	game.inputMapping = mapping
+	game.gamepads = input.NewGamepads(mapping)
+}
+
+// stepEvents returns the events for one simulation step, from the replay if replaying,
+// and records them if recording
+func (game *Game) stepEvents(liveEvents *intsets.Sparse) *intsets.Sparse {
+	events := liveEvents
+	if game.player != nil {
+		replayed, ok := game.player.Next()
+		if ok {
+			events = replayed
+		} else {
+			log.Println("replay finished, keyboard takes over")
+			game.player.Close()
+			game.player = nil
+		}
 	}
-	if kbState[int(sdl.SCANCODE_F1)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F1))
+
+	if game.recorder != nil {
+		if err := game.recorder.Record(events); err != nil {
+			log.Printf("failed to record events, stop recording: %s", err)
+			game.recorder.Close()
+			game.recorder = nil
+		}
 	}
-	if kbState[int(sdl.SCANCODE_F2)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F2))
+
+	return events
+}
+
+func (game *Game) startRecording() {
+	header := replay.Header{
+		LevelName: game.currentLevel.Spec.Name,
+		HeroGrade: game.currentLevel.TheHero.GetGrade(),
+		HeroLives: game.currentLevel.TheHero.GetLives(),
+		Coins:     game.currentLevel.Coins,
 	}
-	if kbState[int(sdl.SCANCODE_F3)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F3))
+	recorder, err := replay.CreateRecorder(game.recordFile, header)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if kbState[int(sdl.SCANCODE_F4)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F4))
+	game.recorder = recorder
+}
+
+// startReplay loads the replay file and puts the game into the state when the replay was recorded
+func (game *Game) startReplay() {
+	player, err := replay.OpenPlayer(game.replayFile)
+	if err != nil {
+		log.Fatal(err)
 	}
-	if kbState[int(sdl.SCANCODE_F5)] == 1 {
-		events.Insert(int(event.EVENT_KEYDOWN_F5))
+	header := player.Header()
+
+	spec, ok := game.levelSpecs[header.LevelName]
+	if !ok {
+		log.Fatalf("level of replay not found: %s", header.LevelName)
 	}
-	return &events
+	game.levelCache = make(map[string]*level.Level)
+	game.currentLevel = game.buildLevel(spec)
+	game.currentLevel.TheHero.RestoreState(header.HeroGrade, header.HeroLives)
+	game.currentLevel.Coins = header.Coins
+
+	game.player = player
 }
 
 // updateCamPos update the position of camera based on hero's position
@@ -181,59 +333,159 @@ func (game *Game) updateCamPos() {
 	}
 }
 
-func (game *Game) handleGlobalEvents(events *intsets.Sparse) {
-	if events.Has(int(event.EVENT_KEYDOWN_F1)) {
+func (game *Game) handleGlobalEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_F1) {
 		game.currentLevel.Restart()
 	}
-	if events.Has(int(event.EVENT_KEYDOWN_F5)) {
+	if input.IsPressed(event.EVENT_KEYDOWN_F5) {
 		// FIXME
 		game.switchLevel("level-0")
 	}
+	if input.IsPressed(event.EVENT_KEYDOWN_F6) {
+		game.enterEditor()
+	}
+	if input.IsPressed(event.EVENT_KEYDOWN_F7) {
+		game.quickSave = game.currentLevel.Snapshot()
+		log.Printf("level %s saved", game.quickSave.LevelName)
+	}
+	if input.IsPressed(event.EVENT_KEYDOWN_F8) {
+		game.quickLoad()
+	}
 }
 
-func (game *Game) loadLevels() {
-	fileInfos, err := ioutil.ReadDir(level_dir)
-	if err != nil {
-		log.Fatal(err)
+// quickLoad restores the snapshot saved by quick save, going back to its level if hero has left it
+func (game *Game) quickLoad() {
+	if game.quickSave == nil {
+		log.Println("nothing to load, press F7 to save first")
+		return
+	}
+	if game.recorder != nil || game.player != nil {
+		log.Println("quick load is not available when recording or replaying")
+		return
 	}
 
-	for _, info := range fileInfos {
-		if info.IsDir() {
-			continue
+	l := game.currentLevel
+	if l.Spec.Name != game.quickSave.LevelName {
+		spec, ok := game.levelSpecs[game.quickSave.LevelName]
+		if !ok {
+			log.Printf("level of quick save not found: %s", game.quickSave.LevelName)
+			return
 		}
-		spec := level.ParseLevelSpec(level_dir + "/" + info.Name())
-		if _, ok := game.levelSpecs[spec.Name]; ok {
-			log.Fatalf("level %s already exists, cannot load again (is there duplicated level names?)", spec.Name)
-		}
-		game.levelSpecs[spec.Name] = spec
+		l = game.buildLevel(spec)
+		// hero keeps the same, its state is restored with the level
+		l.TheHero = game.currentLevel.TheHero
+	}
+	if err := l.RestoreSnapshot(game.quickSave); err != nil {
+		log.Printf("failed to load quick save: %v", err)
+		return
+	}
+	if l != game.currentLevel {
+		// a kept state of the level is out of date
+		delete(game.levelCache, l.Spec.Name)
+		game.currentLevel = l
 	}
 
-	// check if the next levels of each actually exist
-	for name, spec := range game.levelSpecs {
-		for _, nextLevel := range spec.NextLevelNames {
-			_, ok := game.levelSpecs[nextLevel]
-			if !ok {
-				log.Fatalf("one of %s's next level is %s, but not found", name, nextLevel)
-			}
-		}
+	if l.TheHero.IsDead() {
+		audio.StopMusic()
+	} else {
+		audio.PlayMusic()
+	}
+	log.Printf("level %s loaded", l.Spec.Name)
+}
+
+// enterEditor pauses the game and starts editing current level
+func (game *Game) enterEditor() {
+	if game.recorder != nil || game.player != nil {
+		log.Println("editor is not available when recording or replaying")
+		return
+	}
+	game.clock.Pause()
+	game.editor = editor.NewEditor(game.currentLevel.Spec, game.clock, game.camPos)
+}
+
+// updateEditor runs the editor for a frame, and leaves it to play the edited level if asked
+func (game *Game) updateEditor(liveEvents *intsets.Sparse) {
+	input := game.inputTracker.Next(liveEvents)
+	game.editor.Update()
+
+	if pos, ok := game.editor.PlayTestRequested(); ok {
+		game.leaveEditor(&pos)
+	} else if input.IsPressed(event.EVENT_KEYDOWN_F6) {
+		game.leaveEditor(nil)
+	}
+}
+
+// leaveEditor plays the edited level from hero's start, or from the given position in level
+// The edited level replaces the loaded one until the game quits, saving it to file is up to the editor
+func (game *Game) leaveEditor(playFrom *vector.Pos) {
+	spec := game.editor.Spec()
+	game.editor = nil
+	game.levelSpecs[spec.Name] = spec
+	// a kept state of the level doesn't match the edited spec any more
+	delete(game.levelCache, spec.Name)
+
+	l := game.buildLevel(spec)
+	if playFrom != nil {
+		l.InitHeroPos = *playFrom
 	}
 
+	// hero, coins and score keep unchanged
+	l.TheHero = game.currentLevel.TheHero
+	l.Coins = game.currentLevel.Coins
+	l.Score = game.currentLevel.Score
+
+	game.currentLevel = l
+	game.currentLevel.Init()
+	game.clock.Resume()
+}
+
+func (game *Game) loadLevels() {
+	specs, err := level.LoadLevelSpecs(level_dir)
+	if err != nil {
+		// report all broken levels at once
+		log.Fatalf("failed to load levels:\n%s", err)
+	}
+	game.levelSpecs = specs
+
 	firstLevel, ok := game.levelSpecs[first_level_name]
 	if !ok {
 		log.Fatalf("level not found: %s", first_level_name)
 	}
-	game.currentLevel = level.BuildLevel(firstLevel)
+	game.currentLevel = game.buildLevel(firstLevel)
 }
 
-func (game *Game) switchLevel(levelName string) {
-	nextLevel := level.BuildLevel(game.levelSpecs[levelName])
-
-	// hero keeps unchanged
-	nextLevel.TheHero = game.currentLevel.TheHero
+// buildLevel builds a level from a loaded spec, which has been validated so it should never fail
+func (game *Game) buildLevel(spec *level.LevelSpec) *level.Level {
+	l, err := level.BuildLevel(spec, game.clock)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if game.rewind {
+		l.EnableRewind()
+	}
+	return l
+}
 
-	// coins keeps unchanged
-	nextLevel.Coins = game.currentLevel.Coins
+// startGame starts a new game from the given level, as if the game was just launched
+func (game *Game) startGame(levelName string) {
+	game.levelCache = make(map[string]*level.Level)
+	game.currentLevel = game.buildLevel(game.levelSpecs[levelName])
+	game.currentLevel.Init()
+	game.setState(&playingState{game: game})
+	game.saveProgress()
+}
 
+// switchLevel switches to a next level of a level pipe, which may be "level" or "level:entry"
+func (game *Game) switchLevel(target string) {
+	leaving := game.currentLevel
+	nextLevel, err := level.Switch(game.levelCache, game.levelSpecs, leaving, target)
+	if err != nil {
+		log.Fatal(err)
+	}
 	game.currentLevel = nextLevel
-	game.currentLevel.Init()
+
+	if ms, ok := leaving.ClearTime(); ok {
+		game.progress.RecordTime(leaving.Spec.Name, ms)
+	}
+	game.saveProgress()
 }
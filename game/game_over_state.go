@@ -0,0 +1,56 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+)
+
+var _ gameState = &gameOverState{}
+
+const (
+	game_over_continue = iota
+	game_over_restart
+	game_over_quit_to_title
+)
+
+// gameOverState lets player continue current level or restart the whole game after hero runs out of lives
+type gameOverState struct {
+	game *Game
+	menu menu
+}
+
+func newGameOverState(game *Game) *gameOverState {
+	return &gameOverState{
+		game: game,
+		menu: menu{
+			title: "GAME OVER",
+			items: []string{
+				game_over_continue:      "CONTINUE",
+				game_over_restart:       "RESTART",
+				game_over_quit_to_title: "QUIT TO TITLE",
+			},
+		},
+	}
+}
+
+func (gos *gameOverState) HandleEvents(input *event.Input) {
+	switch gos.menu.Update(input) {
+	case game_over_continue:
+		gos.game.popState()
+		gos.game.currentLevel.Continue()
+	case game_over_restart:
+		gos.game.startGame(first_level_name)
+	case game_over_quit_to_title:
+		gos.game.setState(newTitleState(gos.game))
+	}
+}
+
+func (gos *gameOverState) Update(input *event.Input) {
+	// Do nothing, level is frozen until player chooses how to go on
+}
+
+func (gos *gameOverState) Draw() {
+	graphic.ClearScreenWithColor(sdl.Color{0, 0, 0, 255})
+	gos.menu.Draw()
+}
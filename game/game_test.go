@@ -0,0 +1,202 @@
+package game_test
+
+import (
+	"log"
+	"os"
+	"testing"
+
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/game"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/vector"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestMain(m *testing.M) {
+	// asset paths are relative to the repo root
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+
+	// no window needed to run levels
+	graphic.Init(graphic.NewNullBackend())
+
+	os.Exit(m.Run())
+}
+
+func TestPauseFreezesLevel(t *testing.T) {
+	g := game.NewTestGame(loadTestLevelSpecs(t), "level-0")
+	l := g.CurrentLevel()
+	runSteps(g, 10)
+	if !isFading(l) {
+		t.Fatal("expected level to fade in when started")
+	}
+
+	g.Step(keys(event.EVENT_KEYDOWN_PAUSE))
+	ticks := l.Ticks()
+	runSteps(g, 200)
+	if l.Ticks() != ticks {
+		t.Errorf("expected level time to stop at %d when paused but was %d", ticks, l.Ticks())
+	}
+	if !isFading(l) {
+		t.Error("expected fade in to wait while paused")
+	}
+
+	g.Step(keys(event.EVENT_KEYDOWN_PAUSE))
+	if l.Ticks() != ticks {
+		t.Errorf("expected level time to continue from %d but was %d", ticks, l.Ticks())
+	}
+	runSteps(g, 100)
+	if isFading(l) {
+		t.Error("expected fade in to finish after unpaused")
+	}
+}
+
+func TestPersistentLevelEnteredAsLeft(t *testing.T) {
+	g := game.NewTestGame(loadTestLevelSpecs(t), "level-0")
+	l := g.CurrentLevel()
+	runSteps(g, 10)
+	changeLevel(t, l)
+	want := levelChanges(l)
+
+	g.SwitchLevel("level-0.secret-0")
+	runSteps(g, 100)
+	g.SwitchLevel("level-0:from-secret")
+	if g.CurrentLevel() != l {
+		t.Fatal("expected persistent level to be entered again rather than built again")
+	}
+	if got := levelChanges(l); got != want {
+		t.Errorf("expected level to be entered as it was left with %+v but was %+v", want, got)
+	}
+	runSteps(g, 10)
+	if name := g.CurrentLevel().Spec.Name; name != "level-0" {
+		t.Errorf("expected to keep playing level-0 but level is %s", name)
+	}
+}
+
+func TestNonPersistentLevelBuiltAgain(t *testing.T) {
+	g := game.NewTestGame(loadTestLevelSpecs(t), "level-0")
+	g.SwitchLevel("level-1")
+	l := g.CurrentLevel()
+	runSteps(g, 10)
+	changeLevel(t, l)
+
+	g.SwitchLevel("level-0")
+	g.SwitchLevel("level-1")
+	if g.CurrentLevel() == l {
+		t.Fatal("expected non-persistent level to be built again")
+	}
+	if got := levelChanges(g.CurrentLevel()); got != (changes{}) {
+		t.Errorf("expected level to start over but has %+v", got)
+	}
+}
+
+func TestLevelJumpingToItselfStartsOver(t *testing.T) {
+	g := game.NewTestGame(loadTestLevelSpecs(t), "level-0")
+	l := g.CurrentLevel()
+	runSteps(g, 10)
+	changeLevel(t, l)
+
+	// level-0 is persistent, but the level being played is never kept
+	g.SwitchLevel("level-0")
+	if g.CurrentLevel() == l {
+		t.Fatal("expected level jumping to itself to be built again")
+	}
+	if got := levelChanges(g.CurrentLevel()); got != (changes{}) {
+		t.Errorf("expected level to start over but has %+v", got)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func loadTestLevelSpecs(t *testing.T) map[string]*level.LevelSpec {
+	specs, err := level.LoadLevelSpecs("assets/levels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return specs
+}
+
+func keys(events ...event.Event) *intsets.Sparse {
+	var s intsets.Sparse
+	for _, e := range events {
+		s.Insert(int(e))
+	}
+	return &s
+}
+
+// runSteps runs the game for n steps with no key down
+func runSteps(g *game.Game, n int) {
+	for i := 0; i < n; i++ {
+		g.Step(keys())
+	}
+}
+
+// isFading tells if a screen fade of the level is still going on
+func isFading(l *level.Level) bool {
+	for _, e := range l.Snapshot().Effects {
+		if e.Kind == "screen-fade" && !e.Finished {
+			return true
+		}
+	}
+	return false
+}
+
+// changes counts what hero has changed in a level
+type changes struct {
+	removedTiles   int
+	emptyMythBoxes int
+	deadEnemies    int
+}
+
+func levelChanges(l *level.Level) changes {
+	s := l.Snapshot()
+	c := changes{removedTiles: len(s.RemovedTiles)}
+	for _, ts := range s.Tiles {
+		if ts.Empty {
+			c.emptyMythBoxes++
+		}
+	}
+	for _, es := range s.Enemies {
+		if es.Dead {
+			c.deadEnemies++
+		}
+	}
+	return c
+}
+
+// changeLevel breaks a brick, empties a myth box and kills an enemy of the level, as if hero had done so
+func changeLevel(t *testing.T, l *level.Level) {
+	s := l.Snapshot()
+	if len(s.Tiles) == 0 {
+		t.Fatalf("no myth box in level %s", l.Spec.Name)
+	}
+	s.Tiles[0].Empty = true
+	killed := false
+	for i := range s.Enemies {
+		if s.Enemies[i].Kind == "mushroom" {
+			s.Enemies[i].Dead = true
+			killed = true
+			break
+		}
+	}
+	if !killed {
+		t.Fatalf("no mushroom in level %s", l.Spec.Name)
+	}
+	if err := l.RestoreSnapshot(s); err != nil {
+		t.Fatal(err)
+	}
+
+	for y, row := range l.Spec.LevelArr {
+		for x := range row {
+			if td, ok := l.Spec.TileAt(x, y); ok && td.Type == "brick-yellow" {
+				l.RemoveObstacleTileObject(vector.TileID{int32(x), int32(y)})
+				return
+			}
+		}
+	}
+	t.Fatalf("no brick in level %s", l.Spec.Name)
+}
@@ -0,0 +1,63 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+)
+
+var _ gameState = &levelSelectState{}
+
+// levelSelectState starts a new game from any level unlocked in the profile
+type levelSelectState struct {
+	game   *Game
+	menu   menu
+	levels []string
+}
+
+func newLevelSelectState(game *Game) *levelSelectState {
+	var levels []string
+	for name := range game.levelSpecs {
+		if name == first_level_name || game.progress.IsUnlocked(name) {
+			levels = append(levels, name)
+		}
+	}
+	sort.Strings(levels)
+
+	var items []string
+	for _, name := range levels {
+		item := name
+		if ms, ok := game.progress.BestTimes[name]; ok {
+			item = fmt.Sprintf("%-20s %d:%02d.%d", name, ms/60000, ms/1000%60, ms/100%10)
+		}
+		items = append(items, item)
+	}
+
+	return &levelSelectState{
+		game:   game,
+		menu:   menu{title: "LEVEL SELECT", items: items},
+		levels: levels,
+	}
+}
+
+func (lss *levelSelectState) HandleEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_PAUSE) {
+		lss.game.popState()
+		return
+	}
+	if i := lss.menu.Update(input); i >= 0 {
+		lss.game.startGame(lss.levels[i])
+	}
+}
+
+func (lss *levelSelectState) Update(input *event.Input) {
+	// Do nothing
+}
+
+func (lss *levelSelectState) Draw() {
+	graphic.ClearScreenWithColor(sdl.Color{0, 0, 0, 255})
+	lss.menu.Draw()
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// menu is a list of items chosen with up/down and confirmed with jump
+type menu struct {
+	title  string
+	items  []string
+	choice int
+}
+
+// Update moves the choice, and returns the index of the item confirmed in this step, or -1 if none
+func (m *menu) Update(input *event.Input) int {
+	n := len(m.items)
+	if n == 0 {
+		return -1
+	}
+	if input.IsPressed(event.EVENT_KEYDOWN_UP) {
+		m.choice = (m.choice + n - 1) % n
+	}
+	if input.IsPressed(event.EVENT_KEYDOWN_DOWN) {
+		m.choice = (m.choice + 1) % n
+	}
+	if input.IsPressed(event.EVENT_KEYDOWN_SPACE) {
+		return m.choice
+	}
+	return -1
+}
+
+// Draw renders the menu at the middle of screen
+func (m *menu) Draw() {
+	x := int32(graphic.SCREEN_WIDTH/2 - 100)
+	y := int32(graphic.SCREEN_HEIGHT/2 - 80)
+	white := sdl.Color{255, 255, 255, 0}
+	graphic.DrawText(m.title, vector.Pos{x, y}, white)
+
+	for i, item := range m.items {
+		prefix := "  "
+		if i == m.choice {
+			prefix = "> "
+		}
+		graphic.DrawText(prefix+item, vector.Pos{x, y + 60 + int32(i)*30}, white)
+	}
+}
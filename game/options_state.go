@@ -0,0 +1,93 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+)
+
+var _ gameState = &optionsState{}
+
+const (
+	option_music = iota
+	option_sound
+	option_show_fps
+	option_back
+)
+
+// options are settings changed in the options menu, they last until the game quits
+type options struct {
+	musicOn bool
+	soundOn bool
+	showFPS bool
+}
+
+var defaultOptions = options{musicOn: true, soundOn: true, showFPS: true}
+
+// optionsState toggles options, it can be entered from title or the pause menu
+type optionsState struct {
+	game *Game
+	menu menu
+}
+
+func newOptionsState(game *Game) *optionsState {
+	ops := &optionsState{
+		game: game,
+		menu: menu{title: "OPTIONS"},
+	}
+	ops.updateItems()
+	return ops
+}
+
+func (ops *optionsState) HandleEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_PAUSE) {
+		ops.game.popState()
+		return
+	}
+
+	opts := &ops.game.options
+	switch ops.menu.Update(input) {
+	case option_music:
+		opts.musicOn = !opts.musicOn
+		audio.SetMusicOn(opts.musicOn)
+	case option_sound:
+		opts.soundOn = !opts.soundOn
+		audio.SetSoundOn(opts.soundOn)
+	case option_show_fps:
+		opts.showFPS = !opts.showFPS
+	case option_back:
+		ops.game.popState()
+	}
+	ops.updateItems()
+}
+
+func (ops *optionsState) Update(input *event.Input) {
+	// Do nothing
+}
+
+func (ops *optionsState) Draw() {
+	graphic.ClearScreenWithColor(sdl.Color{0, 0, 0, 255})
+	ops.menu.Draw()
+}
+
+func (ops *optionsState) updateItems() {
+	opts := ops.game.options
+	ops.menu.items = []string{
+		option_music:    "MUSIC     " + onOff(opts.musicOn),
+		option_sound:    "SOUND     " + onOff(opts.soundOn),
+		option_show_fps: "SHOW FPS  " + onOff(opts.showFPS),
+		option_back:     "BACK",
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func onOff(on bool) string {
+	if on {
+		return "ON"
+	}
+	return "OFF"
+}
@@ -0,0 +1,80 @@
+package game
+
+import (
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/event"
+)
+
+var _ gameState = &pausedState{}
+
+const (
+	pause_resume = iota
+	pause_options
+	pause_quit_to_title
+)
+
+// pausedState freezes current level, and shows it under a menu
+type pausedState struct {
+	game *Game
+	menu menu
+}
+
+func newPausedState(game *Game) *pausedState {
+	return &pausedState{
+		game: game,
+		menu: menu{
+			title: "PAUSED",
+			items: []string{
+				pause_resume:        "RESUME",
+				pause_options:       "OPTIONS",
+				pause_quit_to_title: "QUIT TO TITLE",
+			},
+		},
+	}
+}
+
+func (ps *pausedState) HandleEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_PAUSE) {
+		ps.game.unpause()
+		return
+	}
+
+	switch ps.menu.Update(input) {
+	case pause_resume:
+		ps.game.unpause()
+	case pause_options:
+		ps.game.pushState(newOptionsState(ps.game))
+	case pause_quit_to_title:
+		ps.game.unpause()
+		ps.game.setState(newTitleState(ps.game))
+	}
+}
+
+func (ps *pausedState) Update(input *event.Input) {
+	// Do nothing, level is frozen
+}
+
+func (ps *pausedState) Draw() {
+	ps.game.drawLevel()
+	ps.menu.Draw()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Game methods
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// pause freezes the time of current level and audio, and shows the pause menu
+func (game *Game) pause() {
+	game.currentLevel.Suspend()
+	audio.PauseMusic()
+	audio.PauseSounds()
+	game.pushState(newPausedState(game))
+}
+
+// unpause goes back to playing from the pause menu
+func (game *Game) unpause() {
+	game.popState()
+	game.currentLevel.Resume()
+	audio.ResumeMusic()
+	audio.ResumeSounds()
+}
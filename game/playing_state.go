@@ -0,0 +1,87 @@
+package game
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/overlay"
+)
+
+var _ gameState = &playingState{}
+
+// playingState runs current level
+type playingState struct {
+	game *Game
+}
+
+func (ps *playingState) HandleEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_PAUSE) {
+		ps.game.pause()
+		return
+	}
+
+	// game event handling
+	ps.game.handleGlobalEvents(input)
+
+	// level event handling
+	ps.game.currentLevel.HandleEvents(input)
+}
+
+func (ps *playingState) Update(input *event.Input) {
+	game := ps.game
+	if game.editor != nil {
+		// editing by the events of this step
+		return
+	}
+
+	// update current level
+	game.currentLevel.Update(input)
+
+	// check if need to switch level
+	nextLevel, shouldSwitchLevel := game.currentLevel.GetNextLevel()
+	if shouldSwitchLevel {
+		game.switchLevel(nextLevel)
+		game.frameDone = true
+		return
+	}
+
+	// no more levels to go, start the campaign over
+	if game.currentLevel.Completed() {
+		game.switchLevel(first_level_name)
+		game.frameDone = true
+		return
+	}
+
+	if game.currentLevel.GameOver() {
+		game.pushState(newGameOverState(game))
+	}
+}
+
+func (ps *playingState) Draw() {
+	ps.game.drawLevel()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Game methods
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// drawLevel renders current level with overlays, as it is when playing
+func (game *Game) drawLevel() {
+	// update camera position
+	game.updateCamPos()
+
+	// start render
+	graphic.ClearScreenWithColor(game.currentLevel.BGColor)
+
+	// render current level
+	game.currentLevel.Draw(game.camPos)
+
+	// render overlays
+	// they get real ticks rather than game ticks, e.g. FPS should still work when game time is frozen
+	for _, ol := range game.overlays {
+		if _, isFPS := ol.(*overlay.FPSOverlay); isFPS && !game.options.showFPS {
+			continue
+		}
+		ol.Draw(game.currentLevel, sdl.GetTicks())
+	}
+}
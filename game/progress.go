@@ -0,0 +1,75 @@
+package game
+
+import (
+	"log"
+
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/save"
+)
+
+// openSaveStore finds where save files are, and loads progress of current profile slot
+func (game *Game) openSaveStore() {
+	dir, err := save.DefaultDir()
+	if err != nil {
+		log.Printf("progress will not be saved: %s", err)
+		return
+	}
+	game.saveStore = save.NewStore(dir)
+	game.loadProgress(game.saveSlot)
+}
+
+// loadProgress switches to a profile slot, a broken save file is reported and starts over
+func (game *Game) loadProgress(slot int) {
+	game.saveSlot = slot
+	game.progress = save.NewProgress()
+	if game.saveStore == nil {
+		return
+	}
+	progress, err := game.saveStore.Load(slot)
+	if err != nil {
+		log.Printf("failed to load progress of slot %d, start over: %s", slot, err)
+		return
+	}
+	game.progress = progress
+}
+
+// saveProgress keeps the state of hero entering current level, it is called on level transitions
+func (game *Game) saveProgress() {
+	l := game.currentLevel
+	p := game.progress
+	p.Unlock(l.Spec.Name)
+	if l.Spec.Secret {
+		p.CollectSecret(l.Spec.Name)
+	}
+	p.CurrentLevel = l.Spec.Name
+	p.Lives = l.TheHero.GetLives()
+	p.HeroGrade = l.TheHero.GetGrade()
+	p.Coins = l.Coins
+	p.Score = l.Score
+
+	// a replay must not change the progress of player
+	if game.saveStore == nil || game.player != nil {
+		return
+	}
+	if err := game.saveStore.Save(game.saveSlot, p); err != nil {
+		log.Printf("failed to save progress: %s", err)
+	}
+}
+
+// canContinue tells if there is a saved game to continue
+func (game *Game) canContinue() bool {
+	_, ok := game.levelSpecs[game.progress.CurrentLevel]
+	return ok && game.progress.Lives > 0
+}
+
+// continueGame starts playing the saved game from where hero entered the level
+func (game *Game) continueGame() {
+	p := game.progress
+	game.levelCache = make(map[string]*level.Level)
+	game.currentLevel = game.buildLevel(game.levelSpecs[p.CurrentLevel])
+	game.currentLevel.TheHero.RestoreState(p.HeroGrade, p.Lives)
+	game.currentLevel.Coins = p.Coins
+	game.currentLevel.Score = p.Score
+	game.currentLevel.Init()
+	game.setState(&playingState{game: game})
+}
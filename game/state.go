@@ -0,0 +1,45 @@
+package game
+
+import "github.com/zenja/mario/event"
+
+// gameState is a screen of the game, such as title, playing or paused
+// States are stacked, only the top one handles events and updates, e.g. pausing pushes a paused state on playing
+type gameState interface {
+	// HandleEvents reacts on input of a simulation step, e.g. choosing a menu item
+	HandleEvents(input *event.Input)
+
+	// Update runs a simulation step
+	Update(input *event.Input)
+
+	// Draw renders a frame
+	Draw()
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Game methods
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// state returns the state on top of the stack, which is the one running
+func (game *Game) state() gameState {
+	return game.states[len(game.states)-1]
+}
+
+func (game *Game) pushState(s gameState) {
+	game.states = append(game.states, s)
+	game.frameDone = true
+}
+
+// popState removes the top state, the one below runs again
+func (game *Game) popState() {
+	if len(game.states) <= 1 {
+		return
+	}
+	game.states = game.states[:len(game.states)-1]
+	game.frameDone = true
+}
+
+// setState drops all states and runs the given one
+func (game *Game) setState(s gameState) {
+	game.states = []gameState{s}
+	game.frameDone = true
+}
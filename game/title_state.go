@@ -0,0 +1,73 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/save"
+)
+
+var _ gameState = &titleState{}
+
+// titleState is the first screen when the game is launched
+type titleState struct {
+	game *Game
+	menu menu
+
+	// what to do for each menu item, items change with the profile slot chosen
+	actions []func()
+}
+
+func newTitleState(game *Game) *titleState {
+	ts := &titleState{
+		game: game,
+		menu: menu{title: "SUPER MARIO"},
+	}
+	ts.updateItems()
+	return ts
+}
+
+func (ts *titleState) HandleEvents(input *event.Input) {
+	if i := ts.menu.Update(input); i >= 0 {
+		ts.actions[i]()
+	}
+}
+
+func (ts *titleState) Update(input *event.Input) {
+	// Do nothing
+}
+
+func (ts *titleState) Draw() {
+	graphic.ClearScreenWithColor(sdl.Color{0, 0, 0, 255})
+	ts.menu.Draw()
+}
+
+func (ts *titleState) updateItems() {
+	game := ts.game
+	ts.menu.items = nil
+	ts.actions = nil
+	add := func(item string, action func()) {
+		ts.menu.items = append(ts.menu.items, item)
+		ts.actions = append(ts.actions, action)
+	}
+
+	if game.canContinue() {
+		add("CONTINUE", game.continueGame)
+	}
+	add("NEW GAME", func() { game.startGame(first_level_name) })
+	add("LEVEL SELECT", func() { game.pushState(newLevelSelectState(game)) })
+	add("OPTIONS", func() { game.pushState(newOptionsState(game)) })
+	add(fmt.Sprintf("PROFILE   %d", game.saveSlot), func() {
+		game.loadProgress(game.saveSlot%save.NUM_SLOTS + 1)
+		ts.updateItems()
+		// keep choosing profile, continue may have come or gone above
+		ts.menu.choice = len(ts.menu.items) - 2
+	})
+	add("QUIT", func() { game.running = false })
+
+	if ts.menu.choice >= len(ts.menu.items) {
+		ts.menu.choice = 0
+	}
+}
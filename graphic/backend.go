@@ -0,0 +1,43 @@
+package graphic
+
+import (
+	"image"
+	_ "image/png"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/vector"
+)
+
+// Backend is where the drawing actually happens
+// The game picks one explicitly at startup via Init, e.g. an SDL window, or an offscreen one for tests
+type Backend interface {
+	Init() error
+	Destroy()
+
+	// LoadResource loads an image file as a resource of the given size, the image is scaled if needed
+	LoadResource(filename string, width, height int32, isTile, flipHorizontal, flipVertical bool) (Resource, error)
+	DestroyResource(resource Resource)
+
+	RenderResource(resource Resource, srcRect *sdl.Rect, dstRect *sdl.Rect)
+	DrawText(text string, pos vector.Pos, color sdl.Color)
+	DrawRect(rect *sdl.Rect, color sdl.Color)
+	ClearScreenWithColor(color sdl.Color)
+	ShowScreen()
+}
+
+// imageSize reads the width and height of an image file without decoding the whole image
+func imageSize(filename string) (int32, int32, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to open image %s", filename)
+	}
+	defer f.Close()
+
+	conf, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed to decode image %s", filename)
+	}
+	return int32(conf.Width), int32(conf.Height), nil
+}
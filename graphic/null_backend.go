@@ -0,0 +1,81 @@
+package graphic
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/vector"
+)
+
+// assert nullBackend is a Backend
+var _ Backend = &nullBackend{}
+
+// nullBackend draws nothing and needs no display
+// Resources still have their real sizes, so hit boxes etc. are the same as with a window
+type nullBackend struct{}
+
+func NewNullBackend() Backend {
+	return &nullBackend{}
+}
+
+func (nb *nullBackend) Init() error {
+	return nil
+}
+
+func (nb *nullBackend) Destroy() {
+	// Do nothing
+}
+
+func (nb *nullBackend) LoadResource(
+	filename string,
+	width,
+	height int32,
+	isTile bool,
+	flipHorizontal bool,
+	flipVertical bool) (Resource, error) {
+
+	return &nullResource{w: width, h: height}, nil
+}
+
+func (nb *nullBackend) DestroyResource(resource Resource) {
+	// Do nothing
+}
+
+func (nb *nullBackend) RenderResource(resource Resource, srcRect *sdl.Rect, dstRect *sdl.Rect) {
+	// Do nothing
+}
+
+func (nb *nullBackend) DrawText(text string, pos vector.Pos, color sdl.Color) {
+	// Do nothing
+}
+
+func (nb *nullBackend) DrawRect(rect *sdl.Rect, color sdl.Color) {
+	// Do nothing
+}
+
+func (nb *nullBackend) ClearScreenWithColor(color sdl.Color) {
+	// Do nothing
+}
+
+func (nb *nullBackend) ShowScreen() {
+	// Do nothing
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// nullResource
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type nullResource struct {
+	w, h  int32
+	alpha uint8
+}
+
+func (nr *nullResource) GetW() int32 {
+	return nr.w
+}
+
+func (nr *nullResource) GetH() int32 {
+	return nr.h
+}
+
+func (nr *nullResource) SetResourceAlpha(alpha uint8) {
+	nr.alpha = alpha
+}
@@ -4,7 +4,6 @@ import (
 	"log"
 
 	"github.com/veandco/go-sdl2/sdl"
-	"github.com/veandco/go-sdl2/sdl_image"
 	"github.com/zenja/mario/math_utils"
 	"github.com/zenja/mario/vector"
 )
@@ -12,7 +11,6 @@ import (
 type ResourceID int
 
 type Resource interface {
-	GetTexture() *sdl.Texture
 	GetW() int32
 	GetH() int32
 	SetResourceAlpha(alpha uint8)
@@ -66,6 +64,7 @@ const (
 	RESOURCE_TYPE_COIN_3
 
 	RESOURCE_TYPE_GOOD_MUSHROOM
+	RESOURCE_TYPE_ONE_UP_MUSHROOM
 
 	RESOURCE_TYPE_MUSHROOM_ENEMY_0
 	RESOURCE_TYPE_MUSHROOM_ENEMY_1
@@ -96,6 +95,12 @@ const (
 	RESOURCE_TYPE_EATER_FLOWER_0
 	RESOURCE_TYPE_EATER_FLOWER_1
 
+	RESOURCE_TYPE_CHECKPOINT_INACTIVE
+	RESOURCE_TYPE_CHECKPOINT_ACTIVE
+
+	RESOURCE_TYPE_GOAL_POLE
+	RESOURCE_TYPE_GOAL_FLAG
+
 	RESOURCE_TYPE_BLACK_SCREEN
 
 	RESOURCE_TYPE_HERO_0_STAND_LEFT
@@ -121,6 +126,9 @@ const (
 	RESOURCE_TYPE_HERO_2_STAND_RIGHT
 	RESOURCE_TYPE_HERO_2_WALKING_RIGHT
 	RESOURCE_TYPE_HERO_2_JUMP_RIGHT
+
+	// IDs from here on are allocated by RegisterExtraTileResource
+	RESOURCE_TYPE_EXTRA_START
 )
 
 const TILE_SIZE = 50
@@ -143,53 +151,15 @@ func Res(id ResourceID) Resource {
 	return resourceRegistry[id]
 }
 
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-// TileResource
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-
-type TileResource struct {
-	texture *sdl.Texture
-}
-
-func (tr *TileResource) GetTexture() *sdl.Texture {
-	return tr.texture
-}
-
-func (tr *TileResource) GetW() int32 {
-	return TILE_SIZE
-}
-
-func (tr *TileResource) GetH() int32 {
-	return TILE_SIZE
-}
-
-func (tr *TileResource) SetResourceAlpha(alpha uint8) {
-	tr.GetTexture().SetAlphaMod(alpha)
-}
-
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-// NonTileResource
-////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
-
-type NonTileResource struct {
-	texture *sdl.Texture
-	w, h    int32
-}
-
-func (ntr *NonTileResource) GetTexture() *sdl.Texture {
-	return ntr.texture
-}
-
-func (ntr *NonTileResource) GetW() int32 {
-	return ntr.w
-}
-
-func (ntr *NonTileResource) GetH() int32 {
-	return ntr.h
-}
-
-func (ntr *NonTileResource) SetResourceAlpha(alpha uint8) {
-	ntr.GetTexture().SetAlphaMod(alpha)
+// ResID is the reverse of Res, it returns the ID of a loaded resource, false if the resource is not loaded
+// It is for saving which resource an object shows, e.g. in level snapshots
+func ResID(res Resource) (ResourceID, bool) {
+	for id, r := range resourceRegistry {
+		if r == res {
+			return id, true
+		}
+	}
+	return 0, false
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -235,14 +205,10 @@ func VisibleRectInCamera(rect sdl.Rect, xCamStart, yCamStart int32) (rectInTile
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func DrawRect(rect sdl.Rect, camPos vector.Pos) {
-	r, green, b, a, err := renderer.GetDrawColor()
-	if err != nil {
-		log.Fatalf("failed to get draw color: %s", err)
-	}
-	renderer.SetDrawColor(255, 255, 255, 255)
 	_, rectInCam := VisibleRectInCamera(rect, camPos.X, camPos.Y)
-	renderer.DrawRect(rectInCam)
-	renderer.SetDrawColor(r, green, b, a)
+	if rectInCam != nil {
+		backend.DrawRect(rectInCam, sdl.Color{255, 255, 255, 255})
+	}
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -265,61 +231,58 @@ func DrawResource(resource Resource, levelRect sdl.Rect, camPos vector.Pos) {
 	}
 }
 
-// registerTileResource loads a sprite into a TileResource from a file
-func registerTileResource(filename string, id ResourceID) {
-	surface, err := img.Load(filename)
-	if err != nil {
-		log.Fatal(err)
+// RegisterExtraTileResource allocates a resource ID for a tile sprite which is not built in
+// It lets a new tile type keep its sprite in its own file, usually called from an init()
+// The sprite is loaded with other resources in Init, or at once if Init was already called
+func RegisterExtraTileResource(filename string) ResourceID {
+	id := RESOURCE_TYPE_EXTRA_START + ResourceID(len(extraTileResources))
+	extraTileResources = append(extraTileResources, filename)
+	if backend != nil {
+		registerTileResource(filename, id)
 	}
-	defer surface.Free()
+	return id
+}
 
-	registerTileFromSurface(surface, id)
+// registerTileResource loads a sprite into a tile resource from a file
+func registerTileResource(filename string, id ResourceID) {
+	registerResourceEx(filename, id, TILE_SIZE, TILE_SIZE, true, false, false)
 }
 
-// registerNonTileResource loads a sprite into a NonTileResource from a file
+// registerNonTileResource loads a sprite into a non-tile resource from a file, keeping its original size
 func registerNonTileResource(filename string, id ResourceID) {
-	surface, err := img.Load(filename)
+	width, height, err := imageSize(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer surface.Free()
 
-	registerNonTileFromSurface(surface, id)
+	registerResourceEx(filename, id, width, height, false, false, false)
 }
 
 func registerScaledNonTileResource(filename string, id ResourceID, dstWidth int32, dstHeight int32) {
-	surface, err := img.Load(filename)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer surface.Free()
-
-	registerScaledNonTileFromSurface(surface, id, dstWidth, dstHeight)
+	registerResourceEx(filename, id, dstWidth, dstHeight, false, false, false)
 }
 
 func registerFlippedNonTileResource(filename string, id ResourceID, flipHorizontal bool) {
-	surface, err := img.Load(filename)
+	width, height, err := imageSize(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer surface.Free()
 
-	registerFlippedNonTileFromSurface(surface, id, flipHorizontal)
+	registerResourceEx(filename, id, width, height, false, flipHorizontal, !flipHorizontal)
 }
 
 // RegisterBackgroundResource register a level background resource, scale it to have level's height
 // This function has to be public because it is used when parsing a level
 func RegisterBackgroundResource(filename string, id ResourceID, tilesInY int) {
-	surface, err := img.Load(filename)
+	width, height, err := imageSize(filename)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer surface.Free()
 
 	dstHeight := int32(tilesInY * TILE_SIZE)
-	dstWidth := surface.W * (dstHeight / surface.H)
+	dstWidth := width * (dstHeight / height)
 
-	registerScaledNonTileFromSurface(surface, id, dstWidth, dstHeight)
+	registerResourceEx(filename, id, dstWidth, dstHeight, false, false, false)
 }
 
 func registerResourceEx(
@@ -331,103 +294,20 @@ func registerResourceEx(
 	flipHorizontal bool,
 	flipVertical bool) {
 
-	surface, err := img.Load(filename)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer surface.Free()
-
-	registerResourceFromSurfaceEx(surface, id, width, height, isTile, flipHorizontal, flipVertical)
-}
-
-// registerTileFromSurface loads a sprite from a surface into a TileResource object
-// User need to free the surface himself
-func registerTileFromSurface(surface *sdl.Surface, id ResourceID) {
-	registerResourceFromSurface(surface, id, TILE_SIZE, TILE_SIZE, true)
-}
-
-// registerNonTileFromSurface loads a sprite from a surface into a NonTileResource object
-// User need to free the surface himself
-func registerNonTileFromSurface(surface *sdl.Surface, id ResourceID) {
-	registerResourceFromSurface(surface, id, surface.W, surface.H, false)
-}
-
-func registerScaledNonTileFromSurface(surface *sdl.Surface, id ResourceID, dstWidth, dstHeight int32) {
-	registerResourceFromSurface(surface, id, dstWidth, dstHeight, false)
-}
-
-func registerFlippedNonTileFromSurface(surface *sdl.Surface, id ResourceID, flipHorizontal bool) {
-	registerFlippedResourceFromSurface(surface, id, surface.W, surface.H, false, flipHorizontal)
-}
-
-// registerResourceFromSurface loads a sprite from a surface into a Resource object
-// User need to free the surface himself
-func registerResourceFromSurface(surface *sdl.Surface, id ResourceID, width, height int32, isTile bool) {
-	registerResourceFromSurfaceEx(surface, id, width, height, isTile, false, false)
-}
-
-func registerFlippedResourceFromSurface(
-	surface *sdl.Surface,
-	id ResourceID,
-	width,
-	height int32,
-	isTile bool,
-	flipHorizontal bool) {
-
-	registerResourceFromSurfaceEx(surface, id, width, height, isTile, flipHorizontal, !flipHorizontal)
-}
-
-func registerResourceFromSurfaceEx(
-	surface *sdl.Surface,
-	id ResourceID,
-	width,
-	height int32,
-	isTile bool,
-	flipHorizontal bool,
-	flipVertical bool) {
-
 	if isTile && (width != TILE_SIZE || height != TILE_SIZE) {
 		log.Fatalf("declared to be tile but has wrong width (%d) or height (%d)", width, height)
 	}
 
-	texture, err := renderer.CreateTextureFromSurface(surface)
+	res, err := backend.LoadResource(filename, width, height, isTile, flipHorizontal, flipVertical)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	// make sure the tile is in good shape
-	if surface.W != width || surface.H != height {
-		oldTexture := texture
-		texture, err = clipTexture(oldTexture, &sdl.Rect{0, 0, width, height})
-		if err != nil {
-			log.Fatal(err)
-		}
-		// release original texture
-		oldTexture.Destroy()
-	}
-
-	// flip texture if needed
-	if flipHorizontal {
-		oldTexture := texture
-		texture, err = flipTexture(texture, width, height, true)
-		oldTexture.Destroy()
-	}
-	if flipVertical {
-		oldTexture := texture
-		texture, err = flipTexture(texture, width, height, false)
-		oldTexture.Destroy()
-	}
-
-	if isTile {
-		resourceRegistry[id] = &TileResource{texture: texture}
-	} else {
-		resourceRegistry[id] = &NonTileResource{texture: texture, w: width, h: height}
-	}
+	resourceRegistry[id] = res
 }
 
 // RenderResource renders a tile (or a part of tile specified by srcRect) to a given position in screen
 func RenderResource(resource Resource, srcRect *sdl.Rect, dstRect *sdl.Rect) {
-	renderer.Copy(resource.GetTexture(), srcRect, dstRect)
+	backend.RenderResource(resource, srcRect, dstRect)
 }
 
 func loadAllResources() {
@@ -471,6 +351,9 @@ func loadAllResources() {
 	// good mushroom
 	registerTileResource("assets/mushroom.png", RESOURCE_TYPE_GOOD_MUSHROOM)
 
+	// 1-up mushroom
+	registerTileResource("assets/mushroom-1up.png", RESOURCE_TYPE_ONE_UP_MUSHROOM)
+
 	// upgrade flower
 	registerTileResource("assets/upgrade-flower.png", RESOURCE_TYPE_UPGRADE_FLOWER)
 
@@ -484,6 +367,10 @@ func loadAllResources() {
 	registerTileResource("assets/water-6.png", RESOURCE_TYPE_WATER_6)
 	registerTileResource("assets/water-pixel.png", RESOURCE_TYPE_WATER_FULL)
 
+	// checkpoint
+	registerTileResource("assets/checkpoint-inactive.png", RESOURCE_TYPE_CHECKPOINT_INACTIVE)
+	registerTileResource("assets/checkpoint-active.png", RESOURCE_TYPE_CHECKPOINT_ACTIVE)
+
 	// -------------------------------
 	// Load non-tile resources
 	// -------------------------------
@@ -519,6 +406,10 @@ func loadAllResources() {
 	// decoration: trees
 	registerNonTileResource("assets/dec-tree-0.png", RESOURCE_TYPE_DEC_TREE_0)
 
+	// goal
+	registerNonTileResource("assets/goal-pole.png", RESOURCE_TYPE_GOAL_POLE)
+	registerNonTileResource("assets/goal-flag.png", RESOURCE_TYPE_GOAL_FLAG)
+
 	// broken pieces
 	registerScaledNonTileResource("assets/brick-piece-red.png", RESOURCE_TYPE_BRICK_PIECE_RED, TILE_SIZE/2, TILE_SIZE/2)
 	registerScaledNonTileResource("assets/brick-piece-yellow.png", RESOURCE_TYPE_BRICK_PIECE_YELLOW, TILE_SIZE/2, TILE_SIZE/2)
@@ -558,4 +449,9 @@ func loadAllResources() {
 
 	// black screen
 	registerScaledNonTileResource("assets/black-pixel.png", RESOURCE_TYPE_BLACK_SCREEN, SCREEN_WIDTH, SCREEN_HEIGHT)
+
+	// extra tiles
+	for i, filename := range extraTileResources {
+		registerTileResource(filename, RESOURCE_TYPE_EXTRA_START+ResourceID(i))
+	}
 }
@@ -0,0 +1,337 @@
+package graphic
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/sdl_image"
+	"github.com/veandco/go-sdl2/sdl_mixer"
+	"github.com/veandco/go-sdl2/sdl_ttf"
+	"github.com/zenja/mario/vector"
+)
+
+// assert sdlBackend is a Backend
+var _ Backend = &sdlBackend{}
+
+// sdlBackend renders to a real SDL window
+type sdlBackend struct {
+	window   *sdl.Window
+	renderer *sdl.Renderer
+	font     *ttf.Font
+}
+
+func NewSDLBackend() Backend {
+	return &sdlBackend{}
+}
+
+func (sb *sdlBackend) Init() error {
+	var err error
+
+	if err = sdl.Init(sdl.INIT_EVERYTHING); err != nil {
+		return errors.Wrap(err, "failed to init sdl")
+	}
+
+	// Create window
+	sb.window, err = sdl.CreateWindow("Mario", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED,
+		SCREEN_WIDTH, SCREEN_HEIGHT, sdl.WINDOW_SHOWN)
+	if err != nil {
+		return errors.Wrap(err, "failed to create window")
+	}
+
+	// Create renderer
+	sb.renderer, err = sdl.CreateRenderer(sb.window, -1, sdl.RENDERER_ACCELERATED|sdl.RENDERER_PRESENTVSYNC)
+	if err != nil {
+		return errors.Wrap(err, "failed to create renderer")
+	}
+
+	// Init font system
+	err = ttf.Init()
+	if err != nil {
+		return errors.Wrap(err, "failed to init font system")
+	}
+
+	// Load font
+	sb.font, err = ttf.OpenFont("assets/fonts/Menlo-Regular.ttf", 18)
+	if err != nil {
+		return errors.Wrap(err, "failed to load font")
+	}
+
+	return nil
+}
+
+func (sb *sdlBackend) Destroy() {
+	sb.renderer.Destroy()
+	sb.window.Destroy()
+
+	mix.Quit()
+	ttf.Quit()
+	img.Quit()
+	sdl.Quit()
+}
+
+func (sb *sdlBackend) LoadResource(
+	filename string,
+	width,
+	height int32,
+	isTile bool,
+	flipHorizontal bool,
+	flipVertical bool) (Resource, error) {
+
+	surface, err := img.Load(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load image %s", filename)
+	}
+	defer surface.Free()
+
+	texture, err := sb.renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create texture")
+	}
+
+	// make sure the tile is in good shape
+	if surface.W != width || surface.H != height {
+		oldTexture := texture
+		texture, err = sb.clipTexture(oldTexture, &sdl.Rect{0, 0, width, height})
+		if err != nil {
+			return nil, err
+		}
+		// release original texture
+		oldTexture.Destroy()
+	}
+
+	// flip texture if needed
+	if flipHorizontal {
+		oldTexture := texture
+		texture, err = sb.flipTexture(texture, width, height, true)
+		oldTexture.Destroy()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if flipVertical {
+		oldTexture := texture
+		texture, err = sb.flipTexture(texture, width, height, false)
+		oldTexture.Destroy()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if isTile {
+		return &TileResource{texture: texture}, nil
+	}
+	return &NonTileResource{texture: texture, w: width, h: height}, nil
+}
+
+func (sb *sdlBackend) DestroyResource(resource Resource) {
+	sdlResource(resource).GetTexture().Destroy()
+}
+
+// RenderResource renders a tile (or a part of tile specified by srcRect) to a given position in screen
+func (sb *sdlBackend) RenderResource(resource Resource, srcRect *sdl.Rect, dstRect *sdl.Rect) {
+	sb.renderer.Copy(sdlResource(resource).GetTexture(), srcRect, dstRect)
+}
+
+func (sb *sdlBackend) DrawText(text string, pos vector.Pos, color sdl.Color) {
+	surface, err := sb.font.RenderUTF8_Solid(text, color)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	texture, err := sb.renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	width := surface.W
+	height := surface.H
+
+	// Free loaded surface
+	surface.Free()
+
+	sb.renderer.Copy(texture, nil, &sdl.Rect{pos.X, pos.Y, width, height})
+}
+
+func (sb *sdlBackend) DrawRect(rect *sdl.Rect, color sdl.Color) {
+	r, green, b, a, err := sb.renderer.GetDrawColor()
+	if err != nil {
+		log.Fatalf("failed to get draw color: %s", err)
+	}
+	sb.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	sb.renderer.DrawRect(rect)
+	sb.renderer.SetDrawColor(r, green, b, a)
+}
+
+func (sb *sdlBackend) ClearScreenWithColor(color sdl.Color) {
+	var err error
+	err = sb.renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	if err != nil {
+		log.Fatal("failed to set renderer draw color")
+	}
+	err = sb.renderer.Clear()
+	if err != nil {
+		log.Fatal("failed to clear renderer")
+	}
+	// reset draw color
+	err = sb.renderer.SetDrawColor(0, 0, 0, 0)
+	if err != nil {
+		log.Fatal("failed to reset renderer draw color")
+	}
+}
+
+func (sb *sdlBackend) ShowScreen() {
+	sb.renderer.Present()
+}
+
+// clipTexture is a helper function to create a new texture from a region of a texture
+// User needs to free the input texture himself if needed
+func (sb *sdlBackend) clipTexture(texture *sdl.Texture, rect *sdl.Rect) (*sdl.Texture, error) {
+	renderer := sb.renderer
+
+	newTexture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_TARGET, int(rect.W), int(rect.H))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clip texture")
+	}
+
+	// will make pixels with alpha 0 fully transparent
+	if err = newTexture.SetBlendMode(sdl.BLENDMODE_BLEND); err != nil {
+		return nil, errors.Wrap(err, "failed to set blend mode")
+	}
+
+	if err = renderer.SetRenderTarget(newTexture); err != nil {
+		return nil, errors.Wrap(err, "failed to set render target")
+	}
+
+	// this together with blend mode will make transparent area
+	if err = renderer.SetDrawColor(0, 0, 0, 0); err != nil {
+		return nil, errors.Wrap(err, "failed to reset draw color")
+	}
+
+	if err = renderer.Clear(); err != nil {
+		return nil, errors.Wrap(err, "failed to clear renderer")
+	}
+
+	if err = renderer.Copy(texture, nil, rect); err != nil {
+		return nil, errors.Wrap(err, "failed to render texture")
+	}
+
+	// reset render target
+	if err = renderer.SetRenderTarget(nil); err != nil {
+		return nil, errors.Wrap(err, "failed to reset render target")
+	}
+
+	return newTexture, nil
+}
+
+// flipTexture is a helper function to create a flipped texture from a region of a texture
+// User needs to free the input texture himself if needed
+func (sb *sdlBackend) flipTexture(texture *sdl.Texture, width int32, height int32, flipHorizontal bool) (*sdl.Texture, error) {
+	renderer := sb.renderer
+
+	newTexture, err := renderer.CreateTexture(sdl.PIXELFORMAT_ARGB8888, sdl.TEXTUREACCESS_TARGET, int(width), int(height))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to clip texture")
+	}
+
+	// will make pixels with alpha 0 fully transparent
+	if err = newTexture.SetBlendMode(sdl.BLENDMODE_BLEND); err != nil {
+		return nil, errors.Wrap(err, "failed to set blend mode")
+	}
+
+	if err = renderer.SetRenderTarget(newTexture); err != nil {
+		return nil, errors.Wrap(err, "failed to set render target")
+	}
+
+	// this together with blend mode will make transparent area
+	if err = renderer.SetDrawColor(0, 0, 0, 0); err != nil {
+		return nil, errors.Wrap(err, "failed to reset draw color")
+	}
+
+	if err := renderer.Clear(); err != nil {
+		return nil, errors.Wrap(err, "failed to clear renderer")
+	}
+
+	var flipFlag sdl.RendererFlip
+	if flipHorizontal {
+		flipFlag = sdl.FLIP_HORIZONTAL
+	} else {
+		flipFlag = sdl.FLIP_VERTICAL
+	}
+	if err := renderer.CopyEx(texture, nil, nil, 0, nil, flipFlag); err != nil {
+		return nil, errors.Wrap(err, "failed to render texture")
+	}
+
+	// reset render target
+	if err = renderer.SetRenderTarget(nil); err != nil {
+		return nil, errors.Wrap(err, "failed to reset render target")
+	}
+	return newTexture, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// SDL resources
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// texturedResource is a Resource backed by an SDL texture
+type texturedResource interface {
+	Resource
+	GetTexture() *sdl.Texture
+}
+
+func sdlResource(resource Resource) texturedResource {
+	tr, ok := resource.(texturedResource)
+	if !ok {
+		log.Fatalf("resource %T is not loaded by the sdl backend", resource)
+	}
+	return tr
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// TileResource
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type TileResource struct {
+	texture *sdl.Texture
+}
+
+func (tr *TileResource) GetTexture() *sdl.Texture {
+	return tr.texture
+}
+
+func (tr *TileResource) GetW() int32 {
+	return TILE_SIZE
+}
+
+func (tr *TileResource) GetH() int32 {
+	return TILE_SIZE
+}
+
+func (tr *TileResource) SetResourceAlpha(alpha uint8) {
+	tr.GetTexture().SetAlphaMod(alpha)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// NonTileResource
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type NonTileResource struct {
+	texture *sdl.Texture
+	w, h    int32
+}
+
+func (ntr *NonTileResource) GetTexture() *sdl.Texture {
+	return ntr.texture
+}
+
+func (ntr *NonTileResource) GetW() int32 {
+	return ntr.w
+}
+
+func (ntr *NonTileResource) GetH() int32 {
+	return ntr.h
+}
+
+func (ntr *NonTileResource) SetResourceAlpha(alpha uint8) {
+	ntr.GetTexture().SetAlphaMod(alpha)
+}
@@ -0,0 +1,7 @@
+//go:build !release
+// +build !release
+
+package input
+
+// debug actions can be bound, build with "-tags release" to turn them off
+const debugKeysAllowed = true
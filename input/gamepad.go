@@ -0,0 +1,144 @@
+package input
+
+import (
+	"log"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"golang.org/x/tools/container/intsets"
+)
+
+// Gamepads keeps the state of all connected game controllers, driven by SDL controller events
+type Gamepads struct {
+	mapping *Mapping
+
+	// key is the instance ID of the controller
+	pads map[sdl.JoystickID]*gamepadState
+
+	// opened controllers, controllers only seen in events (e.g. in tests) are not here
+	controllers map[sdl.JoystickID]*sdl.GameController
+}
+
+type gamepadState struct {
+	buttons map[sdl.GameControllerButton]bool
+	axes    map[sdl.GameControllerAxis]int16
+}
+
+func NewGamepads(mapping *Mapping) *Gamepads {
+	return &Gamepads{
+		mapping:     mapping,
+		pads:        make(map[sdl.JoystickID]*gamepadState),
+		controllers: make(map[sdl.JoystickID]*sdl.GameController),
+	}
+}
+
+// HandleEvent updates gamepads with an SDL event, returns false if it is not a controller event
+// Controllers are opened when connected and closed when disconnected
+func (g *Gamepads) HandleEvent(e sdl.Event) bool {
+	switch e := e.(type) {
+	case *sdl.ControllerDeviceEvent:
+		switch e.Type {
+		case sdl.CONTROLLERDEVICEADDED:
+			// for added event, Which is the device index
+			g.open(int(e.Which))
+		case sdl.CONTROLLERDEVICEREMOVED:
+			// for removed event, Which is the instance ID
+			g.remove(e.Which)
+		}
+	case *sdl.ControllerButtonEvent:
+		g.getPad(e.Which).buttons[sdl.GameControllerButton(e.Button)] = e.State == sdl.PRESSED
+	case *sdl.ControllerAxisEvent:
+		g.getPad(e.Which).axes[sdl.GameControllerAxis(e.Axis)] = e.Value
+	default:
+		return false
+	}
+	return true
+}
+
+// Events returns the actions triggered by any of the gamepads
+func (g *Gamepads) Events() *intsets.Sparse {
+	var events intsets.Sparse
+	for _, pad := range g.pads {
+		for e, buttons := range g.mapping.buttonBindings {
+			for _, btn := range buttons {
+				if pad.buttons[btn] {
+					events.Insert(int(e))
+					break
+				}
+			}
+		}
+
+		// left stick works as D-pad
+		deadzone := g.mapping.deadzone
+		x := pad.axes[sdl.CONTROLLER_AXIS_LEFTX]
+		y := pad.axes[sdl.CONTROLLER_AXIS_LEFTY]
+		if x < -deadzone {
+			events.Insert(int(event.EVENT_KEYDOWN_LEFT))
+		} else if x > deadzone {
+			events.Insert(int(event.EVENT_KEYDOWN_RIGHT))
+		}
+		if y < -deadzone {
+			events.Insert(int(event.EVENT_KEYDOWN_UP))
+		} else if y > deadzone {
+			events.Insert(int(event.EVENT_KEYDOWN_DOWN))
+		}
+	}
+	return &events
+}
+
+// NumConnected returns the number of gamepads known
+func (g *Gamepads) NumConnected() int {
+	return len(g.pads)
+}
+
+// CloseAll closes all opened controllers
+func (g *Gamepads) CloseAll() {
+	for id := range g.pads {
+		g.remove(id)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (g *Gamepads) getPad(id sdl.JoystickID) *gamepadState {
+	pad, ok := g.pads[id]
+	if !ok {
+		pad = &gamepadState{
+			buttons: make(map[sdl.GameControllerButton]bool),
+			axes:    make(map[sdl.GameControllerAxis]int16),
+		}
+		g.pads[id] = pad
+	}
+	return pad
+}
+
+func (g *Gamepads) open(index int) {
+	if !sdl.IsGameController(index) {
+		return
+	}
+	ctrl := sdl.GameControllerOpen(index)
+	if ctrl == nil {
+		log.Printf("failed to open game controller %d", index)
+		return
+	}
+	id := ctrl.GetJoystick().InstanceID()
+	if _, ok := g.controllers[id]; ok {
+		// already opened, SDL may report a controller twice at startup
+		ctrl.Close()
+		return
+	}
+	g.controllers[id] = ctrl
+	g.getPad(id)
+	log.Printf("game controller connected: %s", ctrl.Name())
+}
+
+func (g *Gamepads) remove(id sdl.JoystickID) {
+	if ctrl, ok := g.controllers[id]; ok {
+		log.Printf("game controller disconnected: %s", ctrl.Name())
+		ctrl.Close()
+		delete(g.controllers, id)
+	}
+	delete(g.pads, id)
+}
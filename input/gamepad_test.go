@@ -0,0 +1,69 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/input"
+)
+
+func TestGamepadButtons(t *testing.T) {
+	g := input.NewGamepads(input.NewDefaultMapping())
+
+	g.HandleEvent(&sdl.ControllerButtonEvent{Which: 1, Button: uint8(sdl.CONTROLLER_BUTTON_A), State: sdl.PRESSED})
+	g.HandleEvent(&sdl.ControllerButtonEvent{Which: 1, Button: uint8(sdl.CONTROLLER_BUTTON_DPAD_LEFT), State: sdl.PRESSED})
+	events := g.Events()
+	if !events.Has(int(event.EVENT_KEYDOWN_SPACE)) || !events.Has(int(event.EVENT_KEYDOWN_LEFT)) {
+		t.Errorf("expected jump and left but was %s", events)
+	}
+
+	g.HandleEvent(&sdl.ControllerButtonEvent{Which: 1, Button: uint8(sdl.CONTROLLER_BUTTON_A), State: sdl.RELEASED})
+	events = g.Events()
+	if events.Has(int(event.EVENT_KEYDOWN_SPACE)) {
+		t.Error("expected jump to be released")
+	}
+}
+
+func TestGamepadStickDeadzone(t *testing.T) {
+	g := input.NewGamepads(input.NewDefaultMapping())
+
+	axes := []struct {
+		axis   sdl.GameControllerAxis
+		value  int16
+		expect event.Event
+	}{
+		{sdl.CONTROLLER_AXIS_LEFTX, -20000, event.EVENT_KEYDOWN_LEFT},
+		{sdl.CONTROLLER_AXIS_LEFTX, 20000, event.EVENT_KEYDOWN_RIGHT},
+		{sdl.CONTROLLER_AXIS_LEFTY, -20000, event.EVENT_KEYDOWN_UP},
+		{sdl.CONTROLLER_AXIS_LEFTY, 20000, event.EVENT_KEYDOWN_DOWN},
+	}
+	for _, a := range axes {
+		g.HandleEvent(&sdl.ControllerAxisEvent{Which: 2, Axis: uint8(a.axis), Value: a.value})
+		if events := g.Events(); !events.Has(int(a.expect)) || events.Len() != 1 {
+			t.Errorf("expected only %s for axis %d at %d but was %s", a.expect, a.axis, a.value, events)
+		}
+		// back into deadzone
+		g.HandleEvent(&sdl.ControllerAxisEvent{Which: 2, Axis: uint8(a.axis), Value: input.DEFAULT_DEADZONE / 2})
+		if events := g.Events(); events.Len() != 0 {
+			t.Errorf("expected no event in deadzone but was %s", events)
+		}
+	}
+}
+
+func TestGamepadDisconnect(t *testing.T) {
+	g := input.NewGamepads(input.NewDefaultMapping())
+
+	g.HandleEvent(&sdl.ControllerButtonEvent{Which: 3, Button: uint8(sdl.CONTROLLER_BUTTON_B), State: sdl.PRESSED})
+	if g.NumConnected() != 1 {
+		t.Fatalf("expected 1 gamepad but was %d", g.NumConnected())
+	}
+
+	g.HandleEvent(&sdl.ControllerDeviceEvent{Type: sdl.CONTROLLERDEVICEREMOVED, Which: 3})
+	if g.NumConnected() != 0 {
+		t.Errorf("expected no gamepad but was %d", g.NumConnected())
+	}
+	if events := g.Events(); events.Len() != 0 {
+		t.Errorf("expected no event after disconnect but was %s", events)
+	}
+}
@@ -0,0 +1,247 @@
+package input
+
+import (
+	"github.com/pelletier/go-toml"
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"golang.org/x/tools/container/intsets"
+)
+
+// default deadzone of analog sticks, axis values are in [-32768, 32767]
+const DEFAULT_DEADZONE = 8000
+
+// Mapping maps physical inputs to logical actions, one action can have several bindings
+type Mapping struct {
+	bindings map[event.Event][]sdl.Scancode
+
+	// gamepad
+	buttonBindings map[event.Event][]sdl.GameControllerButton
+	deadzone       int16
+}
+
+func NewMapping() *Mapping {
+	return &Mapping{
+		bindings:       make(map[event.Event][]sdl.Scancode),
+		buttonBindings: make(map[event.Event][]sdl.GameControllerButton),
+		deadzone:       DEFAULT_DEADZONE,
+	}
+}
+
+// NewDefaultMapping returns the mapping used when there is no config file
+func NewDefaultMapping() *Mapping {
+	m := NewMapping()
+	m.Bind(event.EVENT_KEYDOWN_LEFT, sdl.SCANCODE_LEFT)
+	m.Bind(event.EVENT_KEYDOWN_RIGHT, sdl.SCANCODE_RIGHT)
+	m.Bind(event.EVENT_KEYDOWN_UP, sdl.SCANCODE_UP)
+	m.Bind(event.EVENT_KEYDOWN_DOWN, sdl.SCANCODE_DOWN)
+	m.Bind(event.EVENT_KEYDOWN_SPACE, sdl.SCANCODE_SPACE)
+	m.Bind(event.EVENT_KEYDOWN_F, sdl.SCANCODE_F)
+	m.Bind(event.EVENT_KEYDOWN_PAUSE, sdl.SCANCODE_ESCAPE)
+	m.Bind(event.EVENT_KEYDOWN_PAUSE, sdl.SCANCODE_P)
+	m.Bind(event.EVENT_KEYDOWN_REWIND, sdl.SCANCODE_BACKSPACE)
+	m.Bind(event.EVENT_KEYDOWN_F1, sdl.SCANCODE_F1)
+	m.Bind(event.EVENT_KEYDOWN_F2, sdl.SCANCODE_F2)
+	m.Bind(event.EVENT_KEYDOWN_F3, sdl.SCANCODE_F3)
+	m.Bind(event.EVENT_KEYDOWN_F4, sdl.SCANCODE_F4)
+	m.Bind(event.EVENT_KEYDOWN_F5, sdl.SCANCODE_F5)
+	m.Bind(event.EVENT_KEYDOWN_F6, sdl.SCANCODE_F6)
+	m.Bind(event.EVENT_KEYDOWN_F7, sdl.SCANCODE_F7)
+	m.Bind(event.EVENT_KEYDOWN_F8, sdl.SCANCODE_F8)
+	m.bindDefaultButtons()
+	return m
+}
+
+// LoadMapping loads mapping from a TOML config file like:
+//
+//	[keys]
+//	left = ["Left", "A"]
+//	jump = ["Space"]
+//
+//	[gamepad]
+//	deadzone = 8000
+//
+//	[gamepad.buttons]
+//	jump = ["a"]
+//
+//	[debug]
+//	enabled = false
+//
+// key names are SDL scancode names, button names are SDL game controller button names;
+// actions not in the file have no binding, except that default buttons are used when there is no [gamepad] table
+func LoadMapping(filename string) (*Mapping, error) {
+	conf, err := toml.LoadFile(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load input config %s", filename)
+	}
+
+	m := NewMapping()
+	keys, ok := conf.Get("keys").(*toml.Tree)
+	if !ok {
+		return nil, errors.Errorf("input config %s has no [keys] table", filename)
+	}
+	for _, action := range keys.Keys() {
+		e, ok := event.ParseEvent(action)
+		if !ok {
+			return nil, errors.Errorf("unknown action in input config %s: %s", filename, action)
+		}
+		names, ok := keys.Get(action).([]interface{})
+		if !ok {
+			return nil, errors.Errorf("keys of action %s should be an array in input config %s", action, filename)
+		}
+		for _, n := range names {
+			name, ok := n.(string)
+			if !ok {
+				return nil, errors.Errorf("key of action %s should be a string in input config %s", action, filename)
+			}
+			code := sdl.GetScancodeFromName(name)
+			if code == sdl.SCANCODE_UNKNOWN {
+				return nil, errors.Errorf("unknown key of action %s in input config %s: %s", action, filename, name)
+			}
+			m.Bind(e, code)
+		}
+	}
+
+	if err := m.loadGamepad(conf); err != nil {
+		return nil, errors.Wrapf(err, "failed to load gamepad in input config %s", filename)
+	}
+
+	if enabled, ok := conf.GetDefault("debug.enabled", true).(bool); !ok || !enabled {
+		m.DisableDebug()
+	}
+
+	return m, nil
+}
+
+// Bind adds a physical key to an action, debug actions are ignored in release builds
+func (m *Mapping) Bind(e event.Event, code sdl.Scancode) {
+	if e.IsDebug() && !debugKeysAllowed {
+		return
+	}
+	for _, c := range m.bindings[e] {
+		if c == code {
+			return
+		}
+	}
+	m.bindings[e] = append(m.bindings[e], code)
+}
+
+// BindButton adds a gamepad button to an action
+func (m *Mapping) BindButton(e event.Event, btn sdl.GameControllerButton) {
+	if e.IsDebug() && !debugKeysAllowed {
+		return
+	}
+	for _, b := range m.buttonBindings[e] {
+		if b == btn {
+			return
+		}
+	}
+	m.buttonBindings[e] = append(m.buttonBindings[e], btn)
+}
+
+// Unbind removes all bindings of an action
+func (m *Mapping) Unbind(e event.Event) {
+	delete(m.bindings, e)
+	delete(m.buttonBindings, e)
+}
+
+func (m *Mapping) GetBindings(e event.Event) []sdl.Scancode {
+	return m.bindings[e]
+}
+
+func (m *Mapping) GetButtonBindings(e event.Event) []sdl.GameControllerButton {
+	return m.buttonBindings[e]
+}
+
+// SetDeadzone sets the axis value under which analog sticks are considered centered
+func (m *Mapping) SetDeadzone(deadzone int16) {
+	m.deadzone = deadzone
+}
+
+// DisableDebug removes all bindings of debug actions
+func (m *Mapping) DisableDebug() {
+	for e := range m.bindings {
+		if e.IsDebug() {
+			m.Unbind(e)
+		}
+	}
+	for e := range m.buttonBindings {
+		if e.IsDebug() {
+			m.Unbind(e)
+		}
+	}
+}
+
+// Events returns the actions whose keys are down in the given keyboard state,
+// which is indexed by scancode like the one returned by sdl.GetKeyboardState()
+func (m *Mapping) Events(kbState []uint8) *intsets.Sparse {
+	var events intsets.Sparse
+	for e, codes := range m.bindings {
+		for _, c := range codes {
+			if int(c) < len(kbState) && kbState[c] == 1 {
+				events.Insert(int(e))
+				break
+			}
+		}
+	}
+	return &events
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (m *Mapping) bindDefaultButtons() {
+	m.BindButton(event.EVENT_KEYDOWN_LEFT, sdl.CONTROLLER_BUTTON_DPAD_LEFT)
+	m.BindButton(event.EVENT_KEYDOWN_RIGHT, sdl.CONTROLLER_BUTTON_DPAD_RIGHT)
+	m.BindButton(event.EVENT_KEYDOWN_UP, sdl.CONTROLLER_BUTTON_DPAD_UP)
+	m.BindButton(event.EVENT_KEYDOWN_DOWN, sdl.CONTROLLER_BUTTON_DPAD_DOWN)
+	m.BindButton(event.EVENT_KEYDOWN_SPACE, sdl.CONTROLLER_BUTTON_A)
+	m.BindButton(event.EVENT_KEYDOWN_F, sdl.CONTROLLER_BUTTON_B)
+	m.BindButton(event.EVENT_KEYDOWN_F, sdl.CONTROLLER_BUTTON_X)
+	m.BindButton(event.EVENT_KEYDOWN_PAUSE, sdl.CONTROLLER_BUTTON_START)
+	m.BindButton(event.EVENT_KEYDOWN_REWIND, sdl.CONTROLLER_BUTTON_LEFTSHOULDER)
+}
+
+func (m *Mapping) loadGamepad(conf *toml.Tree) error {
+	if !conf.Has("gamepad") {
+		m.bindDefaultButtons()
+		return nil
+	}
+
+	deadzone, ok := conf.GetDefault("gamepad.deadzone", int64(DEFAULT_DEADZONE)).(int64)
+	if !ok {
+		return errors.New("deadzone should be an integer")
+	}
+	if deadzone < 0 || deadzone > 32767 {
+		return errors.Errorf("deadzone should be in [0, 32767] but was %d", deadzone)
+	}
+	m.deadzone = int16(deadzone)
+
+	buttons, ok := conf.GetDefault("gamepad.buttons", &toml.Tree{}).(*toml.Tree)
+	if !ok {
+		return errors.New("gamepad.buttons should be a table")
+	}
+	for _, action := range buttons.Keys() {
+		e, ok := event.ParseEvent(action)
+		if !ok {
+			return errors.Errorf("unknown action: %s", action)
+		}
+		names, ok := buttons.Get(action).([]interface{})
+		if !ok {
+			return errors.Errorf("buttons of action %s should be an array", action)
+		}
+		for _, n := range names {
+			name, ok := n.(string)
+			if !ok {
+				return errors.Errorf("button of action %s should be a string", action)
+			}
+			btn := sdl.GameControllerGetButtonFromString(name)
+			if btn == sdl.CONTROLLER_BUTTON_INVALID {
+				return errors.Errorf("unknown button of action %s: %s", action, name)
+			}
+			m.BindButton(e, btn)
+		}
+	}
+	return nil
+}
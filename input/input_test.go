@@ -0,0 +1,98 @@
+package input_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/input"
+)
+
+func TestSeveralBindingsForOneAction(t *testing.T) {
+	m := input.NewMapping()
+	m.Bind(event.EVENT_KEYDOWN_LEFT, sdl.SCANCODE_LEFT)
+	m.Bind(event.EVENT_KEYDOWN_LEFT, sdl.SCANCODE_A)
+
+	for _, code := range []sdl.Scancode{sdl.SCANCODE_LEFT, sdl.SCANCODE_A} {
+		kbState := make([]uint8, sdl.NUM_SCANCODES)
+		kbState[code] = 1
+		events := m.Events(kbState)
+		if !events.Has(int(event.EVENT_KEYDOWN_LEFT)) {
+			t.Errorf("expected key %d to trigger left", code)
+		}
+		if events.Len() != 1 {
+			t.Errorf("expected 1 event but was %d", events.Len())
+		}
+	}
+}
+
+func TestLoadMapping(t *testing.T) {
+	filename := writeTempConfig(t, `
+[keys]
+left = ["Left", "A"]
+jump = ["Space", "W"]
+debug-upgrade = ["F2"]
+
+[debug]
+enabled = false
+`)
+	defer os.Remove(filename)
+
+	m, err := input.LoadMapping(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetBindings(event.EVENT_KEYDOWN_SPACE); len(got) != 2 {
+		t.Errorf("expected 2 bindings for jump but was %v", got)
+	}
+	if got := m.GetBindings(event.EVENT_KEYDOWN_RIGHT); len(got) != 0 {
+		t.Errorf("expected no binding for right but was %v", got)
+	}
+	if got := m.GetBindings(event.EVENT_KEYDOWN_F2); len(got) != 0 {
+		t.Errorf("expected debug keys to be disabled but was %v", got)
+	}
+	if got := m.GetButtonBindings(event.EVENT_KEYDOWN_SPACE); len(got) != 1 {
+		t.Errorf("expected default gamepad binding for jump but was %v", got)
+	}
+}
+
+func TestLoadMappingUnknownAction(t *testing.T) {
+	filename := writeTempConfig(t, `
+[keys]
+teleport = ["T"]
+`)
+	defer os.Remove(filename)
+
+	if _, err := input.LoadMapping(filename); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func writeTempConfig(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "input-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestPauseIsNotDebug(t *testing.T) {
+	m := input.NewDefaultMapping()
+	m.DisableDebug()
+
+	kbState := make([]uint8, sdl.NUM_SCANCODES)
+	kbState[sdl.SCANCODE_ESCAPE] = 1
+	if !m.Events(kbState).Has(int(event.EVENT_KEYDOWN_PAUSE)) {
+		t.Error("expected escape to pause when debug keys are disabled")
+	}
+}
@@ -0,0 +1,6 @@
+//go:build release
+// +build release
+
+package input
+
+const debugKeysAllowed = false
@@ -67,7 +67,7 @@ func (bte *breakTileEffect) Update(ticks uint32) {
 	for i := range vels {
 		vels[i].Y += 50
 
-		velocityStep := CalcVelocityStep(*vels[i], ticks, bte.lastTicks, nil)
+		velocityStep := CalcVelocityStep(*vels[i], ticks, bte.lastTicks, nil, nil)
 		rects[i].X += velocityStep.X
 		rects[i].Y += velocityStep.Y
 	}
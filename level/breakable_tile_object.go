@@ -57,6 +57,7 @@ func (bto *breakableTileObject) hitByHero(h *Hero, direction hitDirection, level
 
 	// show breaking effect
 	level.AddEffect(NewBreakTileEffect(bto.pieceRes, tid, ticks))
+	level.AddScore(level.ScoreTable.Brick, bto.levelRect)
 
 	// play sound
 	audio.PlaySound(audio.SOUND_BREAK_BRICK)
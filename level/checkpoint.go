@@ -0,0 +1,157 @@
+package level
+
+import (
+	"log"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// RespawnPolicy tells what is restored when hero respawns after death, see Level.Respawn
+type RespawnPolicy struct {
+	// killed enemies come back and living ones go back to where they started
+	ResetEnemies bool
+
+	// broken bricks and emptied myth boxes come back
+	ResetTiles bool
+
+	// collected coins come back, only if enemies are reset since coins are enemies
+	ResetCoins bool
+}
+
+// DefaultRespawnPolicy resets enemies and tiles, but collected coins stay collected
+var DefaultRespawnPolicy = RespawnPolicy{ResetEnemies: true, ResetTiles: true}
+
+func init() {
+	RegisterTileType(TileType{
+		Name:  "checkpoint",
+		Glyph: 'K',
+		Obst:  not_obst,
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewCheckpoint(ctx.TID)
+		},
+	})
+}
+
+// Respawn brings hero back to life at the last checkpoint touched, or where it started if none
+// What else is restored follows RespawnPolicy of the level
+func (l *Level) Respawn() {
+	newLevel, err := BuildLevel(l.Spec, l.clock)
+	if err != nil {
+		// the spec has been built once, it should never happen
+		log.Fatal(err)
+	}
+
+	if l.RespawnPolicy.ResetTiles {
+		l.TileObjects = newLevel.TileObjects
+		l.ObstMngr = newLevel.ObstMngr
+		l.removedTiles = nil
+	}
+
+	if l.RespawnPolicy.ResetEnemies {
+		// coins collected so far, kept across respawns
+		for _, e := range l.Enemies {
+			if c, ok := e.(*coinEnemy); ok && c.IsDead() {
+				l.collectedCoins[c.tid] = true
+			}
+		}
+
+		var enemies []Enemy
+		for _, e := range newLevel.Enemies {
+			switch e := e.(type) {
+			case *coinEnemy:
+				if !l.RespawnPolicy.ResetCoins && l.collectedCoins[e.tid] {
+					continue
+				}
+			case *checkpoint:
+				e.active = l.checkpoint != nil && *l.checkpoint == e.tid
+			}
+			enemies = append(enemies, e)
+		}
+		l.Enemies = enemies
+	}
+
+	l.resetTimer()
+	l.Init()
+	if l.checkpoint != nil {
+		l.TheHero.LiveAndResetPos(checkpointRespawnPos(*l.checkpoint, l.TheHero.GetRect()))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// checkpoint
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var _ Enemy = &checkpoint{}
+
+// checkpoint is a flag which makes hero respawn there after touched
+// It is an "enemy" like coins, so that it is hit by hero, though it never hurts
+type checkpoint struct {
+	basicEnemy
+
+	tid         vector.TileID
+	levelRect   sdl.Rect
+	resInactive graphic.Resource
+	resActive   graphic.Resource
+	active      bool
+}
+
+func NewCheckpoint(tid vector.TileID) *checkpoint {
+	return &checkpoint{
+		tid:         tid,
+		levelRect:   GetTileRect(tid),
+		resInactive: graphic.Res(graphic.RESOURCE_TYPE_CHECKPOINT_INACTIVE),
+		resActive:   graphic.Res(graphic.RESOURCE_TYPE_CHECKPOINT_ACTIVE),
+	}
+}
+
+func (cp *checkpoint) GetRect() sdl.Rect {
+	return cp.levelRect
+}
+
+func (cp *checkpoint) GetZIndex() int {
+	return ZINDEX_1
+}
+
+func (cp *checkpoint) Update(ticks uint32, level *Level) {
+	// another checkpoint may have been touched later
+	cp.active = level.checkpoint != nil && *level.checkpoint == cp.tid
+}
+
+func (cp *checkpoint) Draw(camPos vector.Pos) {
+	if cp.active {
+		graphic.DrawResource(cp.resActive, cp.levelRect, camPos)
+	} else {
+		graphic.DrawResource(cp.resInactive, cp.levelRect, camPos)
+	}
+}
+
+func (cp *checkpoint) hitByHero(h *Hero, direction hitDirection, level *Level, ticks uint32) {
+	if cp.active {
+		return
+	}
+	cp.active = true
+	tid := cp.tid
+	level.checkpoint = &tid
+	audio.PlaySound(audio.SOUND_POWERUP)
+}
+
+func (cp *checkpoint) hitByBottomTile(level *Level, ticks uint32) {
+	// Do nothing
+}
+
+func (cp *checkpoint) hitByFireball(fb *fireball, level *Level, ticks uint32) {
+	// Do nothing
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// checkpointRespawnPos returns where hero of the size respawns to stand at a checkpoint
+func checkpointRespawnPos(tid vector.TileID, heroRect sdl.Rect) vector.Pos {
+	rect := GetTileRect(tid)
+	return vector.Pos{rect.X + (rect.W-heroRect.W)/2, rect.Y + rect.H - heroRect.H}
+}
@@ -0,0 +1,57 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestRespawnAtCheckpoint(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"..........",
+		".H..c..K.B",
+		"BBBBBBBBBB",
+	), clk)
+	l.Init()
+
+	// walk right through the coin and the checkpoint
+	var right intsets.Sparse
+	right.Insert(int(event.EVENT_KEYDOWN_RIGHT))
+	tracker := event.NewTracker()
+	for i := 0; i < 200; i++ {
+		l.Update(tracker.Next(&right))
+		clk.Advance(level.SIMULATION_STEP_MS)
+	}
+	if l.Coins != 1 {
+		t.Fatalf("expected the coin to be collected but coins were %d", l.Coins)
+	}
+
+	l.TheHero.Kill(l)
+	runFrames(l, clk, 500)
+
+	r := l.TheHero.GetRect()
+	if l.TheHero.IsDead() || r.X+r.W/2 < 7*graphic.TILE_SIZE || r.X+r.W/2 > 8*graphic.TILE_SIZE {
+		t.Errorf("expected hero to respawn at the checkpoint but was at %v", r)
+	}
+	// the collected coin doesn't come back, the checkpoint does
+	if len(l.Enemies) != 1 {
+		t.Errorf("expected only the checkpoint after respawn but there were %d enemies", len(l.Enemies))
+	}
+	if l.Coins != 1 {
+		t.Errorf("expected coins to be kept but was %d", l.Coins)
+	}
+
+	// restarting forgets checkpoints and coins collected
+	l.Restart()
+	if r := l.TheHero.GetRect(); r.X != l.InitHeroPos.X {
+		t.Errorf("expected hero to restart at %v but was at %v", l.InitHeroPos, r)
+	}
+	if len(l.Enemies) != 2 {
+		t.Errorf("expected the coin and the checkpoint after restart but there were %d enemies", len(l.Enemies))
+	}
+}
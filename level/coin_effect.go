@@ -41,7 +41,7 @@ func (ci *coinEffect) Update(ticks uint32) {
 	// speed up
 	ci.velocity.Y -= 50
 
-	velocityStep := CalcVelocityStep(ci.velocity, ticks, ci.lastTicks, nil)
+	velocityStep := CalcVelocityStep(ci.velocity, ticks, ci.lastTicks, nil, nil)
 	ci.tileRect.X += velocityStep.X
 	ci.tileRect.Y += velocityStep.Y
 
@@ -10,13 +10,13 @@ import (
 var _ Effect = &deadDownEffect{}
 
 type deadDownEffect struct {
-	res            graphic.Resource
-	levelRect      sdl.Rect
-	velocity       vector.Vec2D
-	startTicks     uint32
-	lastTicks      uint32
-	finished       bool
-	onFinishedHook func()
+	res        graphic.Resource
+	levelRect  sdl.Rect
+	velocity   vector.Vec2D
+	startTicks uint32
+	lastTicks  uint32
+	finished   bool
+	onFinished hook
 }
 
 func NewDeadDownEffect(res graphic.Resource, toRight bool, levelRect sdl.Rect, ticks uint32) *deadDownEffect {
@@ -36,14 +36,14 @@ func NewDeadDownEffect(res graphic.Resource, toRight bool, levelRect sdl.Rect, t
 	}
 }
 
-func NewStraightDeadDownEffect(res graphic.Resource, levelRect sdl.Rect, ticks uint32, onFinishedHook func()) *deadDownEffect {
+func NewStraightDeadDownEffect(res graphic.Resource, levelRect sdl.Rect, ticks uint32, onFinished hook) *deadDownEffect {
 	return &deadDownEffect{
-		res:            res,
-		levelRect:      levelRect,
-		velocity:       vector.Vec2D{0, -1000},
-		startTicks:     ticks,
-		finished:       false,
-		onFinishedHook: onFinishedHook,
+		res:        res,
+		levelRect:  levelRect,
+		velocity:   vector.Vec2D{0, -1000},
+		startTicks: ticks,
+		finished:   false,
+		onFinished: onFinished,
 	}
 }
 
@@ -60,7 +60,7 @@ func (dde *deadDownEffect) Update(ticks uint32) {
 
 	gravity := vector.Vec2D{0, 50}
 	dde.velocity.Add(gravity)
-	velStep := CalcVelocityStep(dde.velocity, ticks, dde.lastTicks, nil)
+	velStep := CalcVelocityStep(dde.velocity, ticks, dde.lastTicks, nil, nil)
 	dde.levelRect.X += velStep.X
 	dde.levelRect.Y += velStep.Y
 
@@ -78,7 +78,5 @@ func (dde *deadDownEffect) Finished() bool {
 }
 
 func (dde *deadDownEffect) OnFinished() {
-	if dde.onFinishedHook != nil {
-		dde.onFinishedHook()
-	}
+	dde.onFinished.run()
 }
@@ -0,0 +1,108 @@
+package level
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// Clone returns a copy of the spec which can be edited without changing the original
+func (spec *LevelSpec) Clone() *LevelSpec {
+	clone := *spec
+	clone.NextLevelNames = append([]string(nil), spec.NextLevelNames...)
+	clone.LevelArr = cloneArr(spec.LevelArr)
+	clone.DecArr = cloneArr(spec.DecArr)
+	clone.Legend = make(map[byte]TileDef)
+	for c, td := range spec.Legend {
+		clone.Legend[c] = td
+	}
+	return &clone
+}
+
+// NewPreviewObject creates what a character of the spec looks like at a tile, e.g. for a palette
+// It returns the tile object, or the enemy if there is no tile object, or the hero; nil if nothing to draw
+// The object is on its own, it doesn't look at its neighbours and should not be added to a level
+func NewPreviewObject(spec *LevelSpec, c byte, tid vector.TileID) Object {
+	td, ok := spec.TileOf(c)
+	if !ok {
+		return nil
+	}
+	t := tileTypesByName[td.Type]
+	ctx := &TileContext{
+		TID: tid,
+		Pos: vector.Pos{tid.X * graphic.TILE_SIZE, tid.Y * graphic.TILE_SIZE},
+		Def: td,
+	}
+
+	switch {
+	case t.NewTile != nil:
+		return t.NewTile(ctx)
+	case t.NewEnemy != nil:
+		return t.NewEnemy(ctx)
+	case t.Name == HeroTileType:
+		return NewHero(ctx.Pos, 0.2, 0.2)
+	}
+	return nil
+}
+
+// SaveLevelDefs writes def and dec-def of the spec back to its TOML level file, the rest of the file is kept
+// The definitions in file must still have the same size as when the spec was parsed
+func SaveLevelDefs(spec *LevelSpec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	if len(spec.Filename) == 0 || spec.DefLine == 0 || spec.DecDefLine == 0 {
+		return errors.Errorf("level %s is not from a TOML level file", spec.Name)
+	}
+
+	info, err := os.Stat(spec.Filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save level %s", spec.Name)
+	}
+	data, err := ioutil.ReadFile(spec.Filename)
+	if err != nil {
+		return errors.Wrapf(err, "failed to save level %s", spec.Name)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	if err := replaceRows(lines, spec.DefLine, spec.LevelArr); err != nil {
+		return errors.Wrapf(err, "failed to save level.def of %s", spec.Filename)
+	}
+	if err := replaceRows(lines, spec.DecDefLine, spec.DecArr); err != nil {
+		return errors.Wrapf(err, "failed to save level.dec-def of %s", spec.Filename)
+	}
+
+	if err := ioutil.WriteFile(spec.Filename, []byte(strings.Join(lines, "\n")), info.Mode()); err != nil {
+		return errors.Wrapf(err, "failed to save level %s", spec.Name)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func cloneArr(arr [][]byte) [][]byte {
+	clone := make([][]byte, len(arr))
+	for i, row := range arr {
+		clone[i] = append([]byte(nil), row...)
+	}
+	return clone
+}
+
+// replaceRows replaces rows starting from a 1-based line, what follows a row in the same line (e.g. closing
+// quotes) is kept
+func replaceRows(lines []string, firstLine int, rows [][]byte) error {
+	for i, row := range rows {
+		n := firstLine - 1 + i
+		if n >= len(lines) || len(lines[n]) < len(row) || strings.Trim(lines[n][len(row):], "\r\"") != "" {
+			return errors.Errorf("line %d has changed since loaded", n+1)
+		}
+		lines[n] = string(row) + lines[n][len(row):]
+	}
+	return nil
+}
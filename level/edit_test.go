@@ -0,0 +1,97 @@
+package level_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenja/mario/level"
+)
+
+func TestSaveLevelDefs(t *testing.T) {
+	content := `[basic]
+name = "save"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "204, 237, 255"
+
+# comments and everything else are kept
+[level]
+def = """
+.H..
+BBBB"""
+
+dec-def = """
+....
+....
+"""
+`
+	filename := writeTempLevel(t, content)
+	defer os.Remove(filename)
+
+	spec, err := level.ParseLevelSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := spec.Clone()
+	edited.LevelArr[1][0] = '.'
+	edited.DecArr[0][3] = '2'
+	if spec.LevelArr[1][0] != 'B' {
+		t.Fatal("clone should not share level definition")
+	}
+
+	if err := level.SaveLevelDefs(edited); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(content, "BBBB\"\"\"", ".BBB\"\"\"", 1)
+	want = strings.Replace(want, "dec-def = \"\"\"\n....", "dec-def = \"\"\"\n...2", 1)
+	if string(data) != want {
+		t.Errorf("expected saved file:\n%s\nbut was:\n%s", want, data)
+	}
+}
+
+func TestSaveLevelDefsChangedFile(t *testing.T) {
+	filename := writeTempLevel(t, `[basic]
+name = "save"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "204, 237, 255"
+
+[level]
+def = """
+.H..
+BBBB
+"""
+
+dec-def = """
+....
+....
+"""
+`)
+	defer os.Remove(filename)
+
+	spec, err := level.ParseLevelSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, []byte("[basic]\nname = \"save\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := level.SaveLevelDefs(spec); err == nil {
+		t.Error("expected an error when the file has changed")
+	}
+}
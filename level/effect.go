@@ -8,3 +8,83 @@ type Effect interface {
 	Finished() bool
 	OnFinished()
 }
+
+type hookKind int
+
+const (
+	hook_none hookKind = iota
+	hook_enable_hero
+	hook_hero_died_down   // hero has died down, fade out the screen
+	hook_hero_faded_out   // screen has faded out after hero died, respawn or game over
+	hook_hero_into_pipe   // hero has gone into a level pipe, switch to LevelName
+	hook_goal_slid_down   // hero has slid down the goal pole, walk off
+	hook_goal_walked_off  // hero has walked off the goal pole, count up bonuses
+	hook_goal_bonus_tally // bonuses are counted up, go to next level or complete
+)
+
+// hook is what a level does when an effect finishes, see Level.runHook
+// It is plain data rather than a closure, so that effects pending can be saved in a Snapshot
+type hook struct {
+	level *Level
+
+	Kind hookKind
+
+	// arguments, which ones are used depends on Kind
+	LivesLeft bool
+	LevelName string
+	FlagBonus int
+	TimeBonus int
+}
+
+// run does what the hook is for, a zero hook does nothing
+func (hk hook) run() {
+	if hk.Kind == hook_none {
+		return
+	}
+	hk.level.runHook(hk)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (l *Level) newHook(kind hookKind) hook {
+	return hook{level: l, Kind: kind}
+}
+
+func (l *Level) runHook(hk hook) {
+	h := l.TheHero
+	switch hk.Kind {
+	case hook_enable_hero:
+		h.Enable()
+
+	case hook_hero_died_down:
+		afterFadeOut := l.newHook(hook_hero_faded_out)
+		afterFadeOut.LivesLeft = hk.LivesLeft
+		l.AddEffect(NewScreenFadeEffectEx(false, 1000, l.Ticks(), afterFadeOut))
+
+	case hook_hero_faded_out:
+		l.afterHeroDied(hk.LivesLeft)
+
+	case hook_hero_into_pipe:
+		l.ShouldSwitchLevel(hk.LevelName)
+		h.Enable()
+
+	case hook_goal_slid_down:
+		afterWalkOff := hk
+		afterWalkOff.Kind = hook_goal_walked_off
+		l.AddEffect(NewHeroWalkOffEffect(h, l.Ticks(), afterWalkOff))
+
+	case hook_goal_walked_off:
+		afterTally := l.newHook(hook_goal_bonus_tally)
+		l.AddEffect(NewLevelCompleteEffect(l, hk.FlagBonus, hk.TimeBonus, l.Ticks(), afterTally))
+
+	case hook_goal_bonus_tally:
+		h.Enable()
+		if len(l.Spec.GoalLevelName) > 0 {
+			l.ShouldSwitchLevel(l.Spec.GoalLevelName)
+		} else {
+			l.completed = true
+		}
+	}
+}
@@ -39,12 +39,25 @@ type Enemy interface {
 
 	// after Kill(), IsDead() should return true
 	Kill()
+
+	// ID tells the enemy apart from others in the level, see EntityID
+	ID() EntityID
+	setID(id EntityID)
 }
 
 type basicEnemy struct {
+	id     EntityID
 	isDead bool
 }
 
+func (be *basicEnemy) ID() EntityID {
+	return be.id
+}
+
+func (be *basicEnemy) setID(id EntityID) {
+	be.id = id
+}
+
 func (be *basicEnemy) IsDead() bool {
 	return be.isDead
 }
@@ -68,6 +81,7 @@ type mushroomEnemy struct {
 	levelRect sdl.Rect
 	lastTicks uint32
 	velocity  vector.Vec2D
+	subPixel  vector.Vec2D
 }
 
 func NewMushroomEnemy(startPos vector.Pos) *mushroomEnemy {
@@ -97,7 +111,7 @@ func (m *mushroomEnemy) Update(ticks uint32, level *Level) {
 		return
 	}
 
-	enemySimpleMove(ticks, m.lastTicks, &m.velocity, &m.levelRect, level)
+	enemySimpleMove(ticks, m.lastTicks, &m.velocity, &m.subPixel, &m.levelRect, level)
 
 	m.updateResource(ticks)
 
@@ -126,6 +140,7 @@ func (m *mushroomEnemy) hitByHero(h *Hero, direction hitDirection, level *Level,
 
 		// add dead effect
 		level.AddEffect(NewShowOnceEffect(m.resHit, GetRectStartPos(m.levelRect), ticks, 500))
+		level.addStompScore(h, m.levelRect)
 
 		audio.PlaySound(audio.SOUND_STOMP)
 	} else {
@@ -144,6 +159,7 @@ func (m *mushroomEnemy) hitByFireball(fb *fireball, level *Level, ticks uint32)
 		dieToRight = true
 	}
 	m.dieDown(dieToRight, level, ticks)
+	level.AddScore(level.ScoreTable.Fireball, m.levelRect)
 }
 
 func (m *mushroomEnemy) dieDown(toRight bool, level *Level, ticks uint32) {
@@ -173,6 +189,7 @@ type tortoiseEnemy struct {
 	isFacingRight bool
 	levelRect     sdl.Rect
 	velocity      vector.Vec2D
+	subPixel      vector.Vec2D
 	lastTicks     uint32
 
 	insideStartTicks uint32 // when tortoise go inside
@@ -211,16 +228,20 @@ func (t *tortoiseEnemy) Update(ticks uint32, level *Level) {
 	onHitLeft := func() {
 		t.isFacingRight = true
 		if t.bumpStartTicks > 0 {
-			level.AddEffect(t.newBangEffect(true))
+			level.AddEffect(t.newBangEffect(true, ticks))
 		}
 	}
 	onHitRight := func() {
 		t.isFacingRight = false
 		if t.bumpStartTicks > 0 {
-			level.AddEffect(t.newBangEffect(false))
+			level.AddEffect(t.newBangEffect(false, ticks))
 		}
 	}
-	enemySimpleMoveEx(ticks, t.lastTicks, &t.velocity, &t.levelRect, level, onHitLeft, onHitRight)
+	enemySimpleMoveEx(ticks, t.lastTicks, &t.velocity, &t.subPixel, &t.levelRect, level, onHitLeft, onHitRight)
+
+	if t.bumpStartTicks > 0 {
+		t.hitEnemiesOnTheWay(level, ticks)
+	}
 
 	t.updateResource(ticks)
 
@@ -293,6 +314,7 @@ func (t *tortoiseEnemy) hitByHero(h *Hero, direction hitDirection, level *Level,
 			t.toInsideState(ticks)
 		}
 
+		level.addStompScore(h, t.levelRect)
 		audio.PlaySound(audio.SOUND_STOMP)
 
 	case HIT_FROM_LEFT_W_INTENT:
@@ -327,6 +349,7 @@ func (t *tortoiseEnemy) hitByFireball(fb *fireball, level *Level, ticks uint32)
 		dieToRight = true
 	}
 	t.dieDown(dieToRight, level, ticks)
+	level.AddScore(level.ScoreTable.Fireball, t.levelRect)
 }
 
 func (t *tortoiseEnemy) dieDown(toRight bool, level *Level, ticks uint32) {
@@ -356,7 +379,31 @@ func (t *tortoiseEnemy) toBumpingState(ticks uint32, toRight bool) {
 	}
 }
 
-func (t *tortoiseEnemy) newBangEffect(hitLeft bool) *showOnceEffect {
+// hitEnemiesOnTheWay kills walking enemies the bumping shell runs into
+func (t *tortoiseEnemy) hitEnemiesOnTheWay(level *Level, ticks uint32) {
+	toRight := t.velocity.X > 0
+	for _, e := range level.Enemies {
+		if e.IsDead() || e == Enemy(t) {
+			continue
+		}
+		rect := e.GetRect()
+		if !t.levelRect.HasIntersection(&rect) {
+			continue
+		}
+		switch e := e.(type) {
+		case *mushroomEnemy:
+			e.dieDown(toRight, level, ticks)
+		case *tortoiseEnemy:
+			e.dieDown(toRight, level, ticks)
+		default:
+			continue
+		}
+		level.AddScore(level.ScoreTable.Shell, rect)
+		audio.PlaySound(audio.SOUND_KICK)
+	}
+}
+
+func (t *tortoiseEnemy) newBangEffect(hitLeft bool, ticks uint32) *showOnceEffect {
 	var xDelta int32
 	if hitLeft {
 		xDelta = -20
@@ -367,7 +414,7 @@ func (t *tortoiseEnemy) newBangEffect(hitLeft bool) *showOnceEffect {
 		t.levelRect.X + xDelta,
 		t.levelRect.Y,
 	}
-	return NewShowOnceEffect(graphic.Res(graphic.RESOURCE_TYPE_BANG), bangStartPos, sdl.GetTicks(), 50)
+	return NewShowOnceEffect(graphic.Res(graphic.RESOURCE_TYPE_BANG), bangStartPos, ticks, 50)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -380,9 +427,13 @@ type eaterFlower struct {
 	basicEnemy
 	*animationTileObj
 
+	// the left tile of the pipe top it grows from
+	tid vector.TileID
+
 	maxY      int32
 	minY      int32
 	goingUp   bool
+	subPixel  vector.Vec2D
 	lastTicks uint32
 }
 
@@ -397,6 +448,7 @@ func NewEaterFlower(tid vector.TileID) *eaterFlower {
 	startY := tidRect.Y - graphic.TILE_SIZE
 	return &eaterFlower{
 		animationTileObj: NewAnimationObject(vector.Pos{startX, startY}, reses, 200, ZINDEX_3),
+		tid:              tid,
 		maxY:             startY,
 		minY:             startY - graphic.TILE_SIZE - res.GetH(),
 		goingUp:          true,
@@ -433,7 +485,7 @@ func (ef *eaterFlower) Update(ticks uint32, level *Level) {
 	} else {
 		velocity.Y = 100
 	}
-	step := CalcVelocityStep(velocity, ticks, ef.lastTicks, nil)
+	step := CalcVelocityStep(velocity, ticks, ef.lastTicks, nil, &ef.subPixel)
 	ef.levelRect.Y += step.Y
 
 	ef.lastTicks = ticks
@@ -458,7 +510,8 @@ func (ef *eaterFlower) hitByFireball(fb *fireball, level *Level, ticks uint32) {
 		ef.levelRect.X,
 		ef.levelRect.Y,
 	}
-	level.AddEffect(NewShowOnceEffect(bangRes, bangStartPos, sdl.GetTicks(), 50))
+	level.AddEffect(NewShowOnceEffect(bangRes, bangStartPos, ticks, 50))
+	level.AddScore(level.ScoreTable.Fireball, ef.levelRect)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -468,10 +521,14 @@ func (ef *eaterFlower) hitByFireball(fb *fireball, level *Level, ticks uint32) {
 type goodMushroom struct {
 	basicEnemy
 
+	// a 1-up mushroom gives an extra life instead of upgrading hero
+	oneUp bool
+
 	res       graphic.Resource
 	levelRect sdl.Rect
 	lastTicks uint32
 	velocity  vector.Vec2D
+	subPixel  vector.Vec2D
 }
 
 func NewGoodMushroom(startPos vector.Pos) *goodMushroom {
@@ -483,6 +540,13 @@ func NewGoodMushroom(startPos vector.Pos) *goodMushroom {
 	}
 }
 
+func NewOneUpMushroom(startPos vector.Pos) *goodMushroom {
+	gm := NewGoodMushroom(startPos)
+	gm.oneUp = true
+	gm.res = graphic.Res(graphic.RESOURCE_TYPE_ONE_UP_MUSHROOM)
+	return gm
+}
+
 func (gm *goodMushroom) GetRect() sdl.Rect {
 	return gm.levelRect
 }
@@ -497,7 +561,7 @@ func (gm *goodMushroom) Update(ticks uint32, level *Level) {
 		return
 	}
 
-	enemySimpleMove(ticks, gm.lastTicks, &gm.velocity, &gm.levelRect, level)
+	enemySimpleMove(ticks, gm.lastTicks, &gm.velocity, &gm.subPixel, &gm.levelRect, level)
 
 	gm.lastTicks = ticks
 }
@@ -508,8 +572,13 @@ func (gm *goodMushroom) Draw(camPos vector.Pos) {
 
 func (gm *goodMushroom) hitByHero(h *Hero, direction hitDirection, level *Level, ticks uint32) {
 	gm.isDead = true
+	if gm.oneUp {
+		level.AddLife(gm.levelRect)
+		return
+	}
 	// upgrade hero
 	h.upgrade(level)
+	level.AddScore(level.ScoreTable.PowerUp, gm.levelRect)
 }
 
 func (gm *goodMushroom) hitByBottomTile(level *Level, ticks uint32) {
@@ -534,6 +603,7 @@ type upgradeFlower struct {
 	levelRect sdl.Rect
 	lastTicks uint32
 	velocity  vector.Vec2D
+	subPixel  vector.Vec2D
 }
 
 func NewUpgradeFlower(startPos vector.Pos) *upgradeFlower {
@@ -559,7 +629,7 @@ func (uf *upgradeFlower) Update(ticks uint32, level *Level) {
 		return
 	}
 
-	enemySimpleMove(ticks, uf.lastTicks, &uf.velocity, &uf.levelRect, level)
+	enemySimpleMove(ticks, uf.lastTicks, &uf.velocity, &uf.subPixel, &uf.levelRect, level)
 
 	uf.lastTicks = ticks
 }
@@ -572,6 +642,7 @@ func (uf *upgradeFlower) hitByHero(h *Hero, direction hitDirection, level *Level
 	uf.isDead = true
 	// upgrade hero to highest
 	h.upgradeToHighest(level)
+	level.AddScore(level.ScoreTable.PowerUp, uf.levelRect)
 }
 
 func (uf *upgradeFlower) hitByBottomTile(level *Level, ticks uint32) {
@@ -636,10 +707,8 @@ func (lj *levelJumper) hitByHero(h *Hero, direction hitDirection, level *Level,
 	}
 
 	h.Disable()
-	afterEffect := func() {
-		level.ShouldSwitchLevel(lj.nextLevelName)
-		h.Enable()
-	}
+	afterEffect := level.newHook(hook_hero_into_pipe)
+	afterEffect.LevelName = lj.nextLevelName
 	level.AddEffect(NewHeroIntoPipeEffect(h, ticks, afterEffect))
 	audio.PlaySound(audio.SOUND_PIPE)
 }
@@ -661,6 +730,8 @@ var _ Enemy = &coinEnemy{}
 type coinEnemy struct {
 	*animationTileObj
 	basicEnemy
+
+	tid vector.TileID
 }
 
 func NewCoinEnemy(tid vector.TileID) *coinEnemy {
@@ -672,6 +743,7 @@ func NewCoinEnemy(tid vector.TileID) *coinEnemy {
 	}
 	return &coinEnemy{
 		animationTileObj: NewAnimationObjectTID(tid, reses, 200, ZINDEX_3),
+		tid:              tid,
 	}
 }
 
@@ -693,7 +765,7 @@ func (ce *coinEnemy) Draw(camPos vector.Pos) {
 
 func (ce *coinEnemy) hitByHero(h *Hero, direction hitDirection, level *Level, ticks uint32) {
 	ce.isDead = true
-	level.Coins++
+	level.AddCoin(ce.levelRect)
 	audio.PlaySound(audio.SOUND_COIN)
 }
 
@@ -713,16 +785,18 @@ func enemySimpleMove(
 	ticks uint32,
 	lastTicks uint32,
 	vel *vector.Vec2D,
+	subPixel *vector.Vec2D,
 	levelRect *sdl.Rect,
 	level *Level) {
 
-	enemySimpleMoveEx(ticks, lastTicks, vel, levelRect, level, nil, nil)
+	enemySimpleMoveEx(ticks, lastTicks, vel, subPixel, levelRect, level, nil, nil)
 }
 
 func enemySimpleMoveEx(
 	ticks uint32,
 	lastTicks uint32,
 	vel *vector.Vec2D,
+	subPixel *vector.Vec2D,
 	levelRect *sdl.Rect,
 	level *Level,
 	onHitLeft func(),
@@ -732,12 +806,15 @@ func enemySimpleMoveEx(
 	vel.Add(gravity)
 
 	maxVel := vector.Vec2D{int32(graphic.TILE_SIZE * 30 / 100), int32(graphic.TILE_SIZE * 30 / 100)}
-	velocityStep := CalcVelocityStep(*vel, ticks, lastTicks, &maxVel)
+	velocityStep := CalcVelocityStep(*vel, ticks, lastTicks, &maxVel, subPixel)
 	levelRect.X += velocityStep.X
 	levelRect.Y += velocityStep.Y
 
 	_, hitRight, hitBottom, hitLeft, _ := level.ObstMngr.SolveCollision(levelRect, SOLVE_COLLISION_ENEMY)
 
+	if hitRight || hitLeft {
+		subPixel.X = 0
+	}
 	if hitRight {
 		vel.X = -vel.X
 		if onHitRight != nil {
@@ -754,6 +831,7 @@ func enemySimpleMoveEx(
 	// prevent too big down velocity
 	if velocityStep.Y > 0 && hitBottom {
 		vel.Y = 0
+		subPixel.Y = 0
 	}
 }
 
@@ -0,0 +1,10 @@
+package level
+
+// IsMarker tells if an enemy only marks a place in level, e.g. checkpoint or goal, rather than being a real enemy
+func IsMarker(e Enemy) bool {
+	switch e.(type) {
+	case *checkpoint, *goal:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package level
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+const (
+	// how fast hero and flag slide down the pole, pixels per second
+	goal_slide_velocity = 300
+
+	// points for each second left on the timer
+	time_bonus_per_second = 50
+)
+
+// flag bonus by how high hero touches the pole, from top
+var flagBonuses = []struct {
+	minHeightRatio float64
+	points         int
+}{
+	{0.9, 5000},
+	{0.6, 2000},
+	{0.4, 800},
+	{0.2, 400},
+	{0, 100},
+}
+
+func init() {
+	// the goal stands on its tile with its pole going up
+	RegisterTileType(TileType{
+		Name:  "goal",
+		Glyph: 'F',
+		Obst:  not_obst,
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewGoal(ctx.TID)
+		},
+	})
+}
+
+// Completed tells if the level is completed and there is no next level to go, see LevelSpec.GoalLevelName
+func (l *Level) Completed() bool {
+	return l.completed
+}
+
+// ClearTime returns level time in milliseconds taken from the start of the level to reaching its goal,
+// false if the goal is not reached
+func (l *Level) ClearTime() (uint32, bool) {
+	return l.clearTicks, l.clearTicks > 0
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// goal
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var _ Enemy = &goal{}
+
+// goal is a flagpole which completes the level when hero touches it
+// It is an enemy for the same reason as checkpoint
+type goal struct {
+	basicEnemy
+
+	tid vector.TileID
+
+	poleRes   graphic.Resource
+	flagRes   graphic.Resource
+	poleRect  sdl.Rect
+	flagRect  sdl.Rect
+	touched   bool
+	lastTicks uint32
+	subPixel  vector.Vec2D
+}
+
+// NewGoal creates a goal whose pole stands on the bottom of the tile
+func NewGoal(tid vector.TileID) *goal {
+	tileRect := GetTileRect(tid)
+	poleRes := graphic.Res(graphic.RESOURCE_TYPE_GOAL_POLE)
+	flagRes := graphic.Res(graphic.RESOURCE_TYPE_GOAL_FLAG)
+	poleRect := sdl.Rect{tileRect.X, tileRect.Y + tileRect.H - poleRes.GetH(), poleRes.GetW(), poleRes.GetH()}
+	return &goal{
+		tid:      tid,
+		poleRes:  poleRes,
+		flagRes:  flagRes,
+		poleRect: poleRect,
+		// flag hangs at the left of the pole, under the ball on its top
+		flagRect: sdl.Rect{poleRect.X - flagRes.GetW()/2, poleRect.Y + 15, flagRes.GetW(), flagRes.GetH()},
+	}
+}
+
+// GetRect returns the pole only, which is what hero touches
+func (g *goal) GetRect() sdl.Rect {
+	return sdl.Rect{g.poleRect.X + g.poleRect.W/2 - 5, g.poleRect.Y, 10, g.poleRect.H}
+}
+
+func (g *goal) GetZIndex() int {
+	return ZINDEX_1
+}
+
+func (g *goal) Update(ticks uint32, level *Level) {
+	if !g.touched {
+		return
+	}
+	if g.lastTicks == 0 {
+		g.lastTicks = ticks
+		return
+	}
+
+	// flag slides down with hero
+	step := CalcVelocityStep(vector.Vec2D{0, goal_slide_velocity}, ticks, g.lastTicks, nil, &g.subPixel)
+	g.flagRect.Y += step.Y
+	if bottom := g.poleRect.Y + g.poleRect.H - graphic.TILE_SIZE; g.flagRect.Y+g.flagRect.H > bottom {
+		g.flagRect.Y = bottom - g.flagRect.H
+	}
+
+	g.lastTicks = ticks
+}
+
+func (g *goal) Draw(camPos vector.Pos) {
+	graphic.DrawResource(g.poleRes, g.poleRect, camPos)
+	graphic.DrawResource(g.flagRes, g.flagRect, camPos)
+}
+
+func (g *goal) hitByHero(h *Hero, direction hitDirection, level *Level, ticks uint32) {
+	if g.touched {
+		return
+	}
+	g.touched = true
+
+	flagBonus := g.flagBonus(h.levelRect)
+	timeBonus := level.timeBonus()
+	level.clearTicks = ticks - level.startTicks
+
+	h.Disable()
+	// hero walks off and then bonuses are counted up, see Level.runHook
+	afterSlideDown := level.newHook(hook_goal_slid_down)
+	afterSlideDown.FlagBonus = flagBonus
+	afterSlideDown.TimeBonus = timeBonus
+	// hero holds the pole at its left until reaching the ground
+	h.levelRect.X = g.GetRect().X - h.levelRect.W
+	level.AddEffect(NewHeroSlideDownEffect(h, g.poleRect.Y+g.poleRect.H, ticks, afterSlideDown))
+
+	audio.StopMusic()
+	audio.PlaySound(audio.SOUND_POWERUP)
+}
+
+func (g *goal) hitByBottomTile(level *Level, ticks uint32) {
+	// Do nothing
+}
+
+func (g *goal) hitByFireball(fb *fireball, level *Level, ticks uint32) {
+	// Do nothing
+}
+
+// flagBonus returns points for touching the pole, the higher the more
+func (g *goal) flagBonus(heroRect sdl.Rect) int {
+	poleBottom := g.poleRect.Y + g.poleRect.H
+	ratio := float64(poleBottom-(heroRect.Y+heroRect.H)) / float64(g.poleRect.H)
+	for _, b := range flagBonuses {
+		if ratio >= b.minHeightRatio {
+			return b.points
+		}
+	}
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// timeBonus returns points for time left on the timer, none if the level has no time limit
+func (l *Level) timeBonus() int {
+	ms, ok := l.TimeLeft()
+	if !ok {
+		return 0
+	}
+	return ms / 1000 * time_bonus_per_second
+}
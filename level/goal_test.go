@@ -0,0 +1,51 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/level"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestGoalCompletesLevel(t *testing.T) {
+	for _, goalLevel := range []string{"", "next"} {
+		clk := clock.NewManualClock(1)
+		spec := newTestSpec(
+			"H....F..",
+			"BBBBBBBB",
+		)
+		spec.GoalLevelName = goalLevel
+		l := mustBuildLevel(t, spec, clk)
+		l.Init()
+
+		// walk right into the pole, then hands off until the sequence ends
+		var right intsets.Sparse
+		right.Insert(int(event.EVENT_KEYDOWN_RIGHT))
+		tracker := event.NewTracker()
+		finished := false
+		for i := 0; i < 1000 && !finished; i++ {
+			events := &right
+			if i > 100 {
+				events = nil
+			}
+			l.Update(tracker.Next(events))
+			clk.Advance(level.SIMULATION_STEP_MS)
+
+			_, shouldSwitch := l.GetNextLevel()
+			finished = shouldSwitch || l.Completed()
+		}
+
+		if nextLevel, _ := l.GetNextLevel(); nextLevel != goalLevel || l.Completed() != (goalLevel == "") {
+			t.Errorf("expected goal to go to %q but next level was %q and completed %v",
+				goalLevel, nextLevel, l.Completed())
+		}
+		if l.Score <= 0 {
+			t.Errorf("expected bonuses added to score but was %d", l.Score)
+		}
+		if ms, ok := l.ClearTime(); !ok || ms == 0 {
+			t.Errorf("expected the time to reach the goal to be known but was %d", ms)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package level_test
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/replay"
+	"golang.org/x/tools/container/intsets"
+)
+
+// run "go test ./level -update" to regenerate golden files after an intended gameplay change
+var update = flag.Bool("update", false, "regenerate golden files")
+
+const (
+	// paths are relative to the repo root, see TestMain
+	levelsDir = "assets/levels"
+	goldenDir = "level/testdata/golden"
+
+	// write a checkpoint every this many simulation steps
+	checkpointInterval = 30
+)
+
+// inputSpan holds a set of keys down for a number of simulation steps
+type inputSpan struct {
+	steps int
+	keys  []event.Event
+}
+
+type goldenCase struct {
+	name string
+
+	// either a level file with a script, or a replay file which knows its level
+	levelFile  string
+	script     []inputSpan
+	replayFile string
+}
+
+var goldenCases = []goldenCase{
+	{
+		name:      "level0-idle",
+		levelFile: "level0.toml",
+		script:    []inputSpan{{steps: 120}},
+	},
+	{
+		name:      "level0-run-and-jump",
+		levelFile: "level0.toml",
+		script: []inputSpan{
+			{steps: 40, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 20, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 60, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 20, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 120, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+		},
+	},
+	{
+		name:      "level0-first-pipe",
+		levelFile: "level0.toml",
+		script: []inputSpan{
+			{steps: 30},
+			// jump is cut short when released, hold it long enough to get onto the pipe
+			{steps: 24, keys: []event.Event{event.EVENT_KEYDOWN_LEFT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 18, keys: []event.Event{event.EVENT_KEYDOWN_LEFT}},
+			{steps: 30},
+			{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_DOWN}},
+			{steps: 150},
+		},
+	},
+	{
+		// stomp and jump the way to the secret pipe, then come back out of the entry of level-0
+		name:      "level0-secret-pipe",
+		levelFile: "level0.toml",
+		script: []inputSpan{
+			{steps: 35, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 221, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 1, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 25, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 2, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 7, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 15, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 90},
+			{steps: 28, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 3, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 126, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 62, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 28, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_DOWN}},
+			{steps: 85},
+			// in the secret room, go down its floors and back through its pipe
+			{steps: 30},
+			{steps: 250, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 250, keys: []event.Event{event.EVENT_KEYDOWN_LEFT}},
+			{steps: 250, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 200, keys: []event.Event{event.EVENT_KEYDOWN_LEFT}},
+			{steps: 15, keys: []event.Event{event.EVENT_KEYDOWN_SPACE}},
+			{steps: 8, keys: []event.Event{event.EVENT_KEYDOWN_LEFT, event.EVENT_KEYDOWN_SPACE}},
+			{steps: 7, keys: []event.Event{event.EVENT_KEYDOWN_SPACE}},
+			{steps: 20},
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_DOWN}},
+			{steps: 100},
+			{steps: 60, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+		},
+	},
+	{
+		name:       "level0-recorded",
+		replayFile: "level/testdata/level0-recorded.mrpl",
+	},
+}
+
+func TestGoldenReplays(t *testing.T) {
+	specs := loadTestLevelSpecs(t)
+
+	for _, c := range goldenCases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			got := runGoldenCase(t, c, specs)
+
+			goldenFile := filepath.Join(goldenDir, c.name+".golden")
+			if *update {
+				if err := os.MkdirAll(goldenDir, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(goldenFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("failed to read golden file, run with -update to create it: %s", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("trace differs from %s, run with -update if the change is intended\ngot:\n%s\nwant:\n%s",
+					goldenFile, got, want)
+			}
+		})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func loadTestLevelSpecs(t *testing.T) map[string]*level.LevelSpec {
+	specs, err := level.LoadLevelSpecs(levelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return specs
+}
+
+// runGoldenCase drives a level step by step and returns the trace to compare with golden file
+func runGoldenCase(t *testing.T, c goldenCase, specs map[string]*level.LevelSpec) []byte {
+	var inputs []*intsets.Sparse
+	var l *level.Level
+	clk := clock.NewManualClock(1)
+
+	if len(c.replayFile) > 0 {
+		player, err := replay.OpenPlayer(c.replayFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer player.Close()
+
+		header := player.Header()
+		spec, ok := specs[header.LevelName]
+		if !ok {
+			t.Fatalf("level of replay not found: %s", header.LevelName)
+		}
+		l = mustBuildLevel(t, spec, clk)
+		l.TheHero.RestoreState(header.HeroGrade, header.HeroLives)
+		l.Coins = header.Coins
+
+		for {
+			events, ok := player.Next()
+			if !ok {
+				break
+			}
+			inputs = append(inputs, events)
+		}
+	} else {
+		spec, err := level.ParseLevelSpec(filepath.Join(levelsDir, c.levelFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		l = mustBuildLevel(t, spec, clk)
+		inputs = expandScript(c.script)
+	}
+	l.Init()
+
+	var trace bytes.Buffer
+	writeCheckpoint(&trace, 0, l)
+	// persistent levels left, entered again as they were
+	cache := make(map[string]*level.Level)
+	tracker := event.NewTracker()
+	for i, events := range inputs {
+		step := i + 1
+		input := tracker.Next(events)
+		l.HandleEvents(input)
+		l.Update(input)
+		clk.Advance(level.SIMULATION_STEP_MS)
+
+		// switch level the same way as the game does
+		if nextLevelName, shouldSwitch := l.GetNextLevel(); shouldSwitch {
+			fmt.Fprintf(&trace, "step %4d: switch %s -> %s\n", step, l.Spec.Name, nextLevelName)
+			next, err := level.Switch(cache, specs, l, nextLevelName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			l = next
+		}
+
+		if step%checkpointInterval == 0 || step == len(inputs) {
+			writeCheckpoint(&trace, step, l)
+		}
+	}
+
+	return trace.Bytes()
+}
+
+func expandScript(script []inputSpan) []*intsets.Sparse {
+	var inputs []*intsets.Sparse
+	for _, span := range script {
+		var events intsets.Sparse
+		for _, k := range span.keys {
+			events.Insert(int(k))
+		}
+		for i := 0; i < span.steps; i++ {
+			inputs = append(inputs, &events)
+		}
+	}
+	return inputs
+}
+
+func writeCheckpoint(w *bytes.Buffer, step int, l *level.Level) {
+	// checkpoints and goals are enemies only to be hit by hero, they are not counted
+	deadEnemies, numEnemies := 0, 0
+	for _, e := range l.Enemies {
+		if level.IsMarker(e) {
+			continue
+		}
+		numEnemies++
+		if e.IsDead() {
+			deadEnemies++
+		}
+	}
+	heroRect := l.TheHero.GetRect()
+	fmt.Fprintf(w, "step %4d: level=%s hero=(%d,%d) grade=%d lives=%d dead=%t coins=%d enemies=%d/%d\n",
+		step, l.Spec.Name, heroRect.X, heroRect.Y, l.TheHero.GetGrade(), l.TheHero.GetLives(),
+		l.TheHero.IsDead(), l.Coins, deadEnemies, numEnemies)
+}
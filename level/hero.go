@@ -8,10 +8,18 @@ import (
 	"github.com/zenja/mario/event"
 	"github.com/zenja/mario/graphic"
 	"github.com/zenja/mario/vector"
-	"golang.org/x/tools/container/intsets"
 )
 
-const hurtAnimationMS = 2000
+const (
+	hurtAnimationMS = 2000
+
+	// Y-velocity of jump, unit is pixels per second
+	jumpVelocity = -1000
+	// when jump key is released early, rising velocity is cut to this to make a lower jump
+	jumpCutVelocity = -400
+	// a jump pressed at most this many steps before landing still happens on landing
+	jumpBufferSteps = 6
+)
 
 // assert &Hero is an Object
 var _ Object = &Hero{}
@@ -75,12 +83,21 @@ type Hero struct {
 	// current velocity, unit is pixels per second
 	velocity vector.Vec2D
 
+	// sub-pixel part of position, see CalcVelocityStep
+	subPixel vector.Vec2D
+
 	lastTicks uint32
 
 	lastFireTicks uint32
 
+	// steps left for a buffered jump, see jumpBufferSteps
+	jumpBuffer int
+
 	isOnGround bool
 
+	// enemies stomped since hero left the ground, see ScoreTable.StompCombo
+	stompCombo int
+
 	isFacingRight bool
 
 	lives int
@@ -184,12 +201,12 @@ func NewHero(
 		velocity:              vector.Vec2D{0, 0},
 		isOnGround:            false,
 		isFacingRight:         true,
-		lives:                 3,
+		lives:                 INIT_LIVES,
 	}
 	return h
 }
 
-func (h *Hero) HandleEvents(events *intsets.Sparse, level *Level) {
+func (h *Hero) HandleEvents(input *event.Input, level *Level) {
 	if h.isDead || h.disabled {
 		return
 	}
@@ -199,37 +216,41 @@ func (h *Hero) HandleEvents(events *intsets.Sparse, level *Level) {
 		h.velocity.X = 0
 	}
 
-	if events.Has(int(event.EVENT_KEYDOWN_LEFT)) {
+	if input.IsHeld(event.EVENT_KEYDOWN_LEFT) {
 		h.isFacingRight = false
 		h.velocity.X = -350
-	} else if events.Has(int(event.EVENT_KEYDOWN_RIGHT)) {
+	} else if input.IsHeld(event.EVENT_KEYDOWN_RIGHT) {
 		h.isFacingRight = true
 		h.velocity.X = 350
 	}
-	if events.Has(int(event.EVENT_KEYDOWN_SPACE)) {
+
+	// jump only when pressed, so that holding jump key does not jump again after landing;
+	// a press just before landing is buffered
+	if input.IsPressed(event.EVENT_KEYDOWN_SPACE) {
+		h.jumpBuffer = jumpBufferSteps
+	}
+	if h.jumpBuffer > 0 {
 		if h.isOnGround {
-			h.velocity.Y = -1000
+			h.velocity.Y = jumpVelocity
+			h.jumpBuffer = 0
+		} else {
+			h.jumpBuffer--
 		}
 	}
-	if events.Has(int(event.EVENT_KEYDOWN_F)) {
-		h.fPressed = true
-	} else {
-		h.fPressed = false
-	}
-	if events.Has(int(event.EVENT_KEYDOWN_UP)) {
-		h.upPressed = true
-	} else {
-		h.upPressed = false
-	}
-	if events.Has(int(event.EVENT_KEYDOWN_DOWN)) {
-		h.downPressed = true
-	} else {
-		h.downPressed = false
+	// releasing jump key while rising makes a lower jump
+	if input.IsReleased(event.EVENT_KEYDOWN_SPACE) && h.velocity.Y < jumpCutVelocity {
+		h.velocity.Y = jumpCutVelocity
 	}
-	if events.Has(int(event.EVENT_KEYDOWN_F2)) {
+
+	h.fPressed = input.IsHeld(event.EVENT_KEYDOWN_F)
+	h.upPressed = input.IsHeld(event.EVENT_KEYDOWN_UP)
+	h.downPressed = input.IsHeld(event.EVENT_KEYDOWN_DOWN)
+
+	// debug keys take effect once per press
+	if input.IsPressed(event.EVENT_KEYDOWN_F2) {
 		h.upgrade(level)
 	}
-	if events.Has(int(event.EVENT_KEYDOWN_F3)) {
+	if input.IsPressed(event.EVENT_KEYDOWN_F3) {
 		h.downgrade()
 	}
 }
@@ -241,7 +262,7 @@ func (h *Hero) Draw(camPos vector.Pos) {
 	}
 
 	// if hurt, blink for a while, otherwise just draw the hero
-	ticks := sdl.GetTicks()
+	ticks := h.lastTicks
 	if h.hurtStartTicks > 0 && ticks-h.hurtStartTicks < hurtAnimationMS {
 		if (ticks-h.hurtStartTicks)%200 > 100 {
 			graphic.DrawResource(h.currRes, h.getRenderRect(), camPos)
@@ -278,7 +299,7 @@ func (h *Hero) Update(ticks uint32, level *Level) {
 	h.velocity.Add(gravity)
 
 	maxVel := vector.Vec2D{int32(graphic.TILE_SIZE * 30 / 100), int32(graphic.TILE_SIZE * 30 / 100)}
-	velocityStep := CalcVelocityStep(h.velocity, ticks, h.lastTicks, &maxVel)
+	velocityStep := CalcVelocityStep(h.velocity, ticks, h.lastTicks, &maxVel, &h.subPixel)
 
 	// apply velocity step
 	h.levelRect.X += velocityStep.X
@@ -316,19 +337,26 @@ func (h *Hero) Update(ticks uint32, level *Level) {
 
 	// is on ground
 	h.isOnGround = hitBottom
+	if hitBottom {
+		h.stompCombo = 0
+	}
 
 	// reset velocity according to collision and direction
 	if velocityStep.X > 0 && hitRight {
 		h.velocity.X = 0
+		h.subPixel.X = 0
 	}
 	if velocityStep.X < 0 && hitLeft {
 		h.velocity.X = 0
+		h.subPixel.X = 0
 	}
 	if velocityStep.Y > 0 && hitBottom {
 		h.velocity.Y = 0
+		h.subPixel.Y = 0
 	}
 	if velocityStep.Y < 0 && hitTop {
 		h.velocity.Y = 0
+		h.subPixel.Y = 0
 	}
 
 	// fire if needed and capable and not too frequent
@@ -363,7 +391,7 @@ func (h *Hero) Hurt(level *Level) {
 	if h.hurtStartTicks == 0 {
 		if h.grade > 0 {
 			h.downgrade()
-			h.hurtStartTicks = sdl.GetTicks()
+			h.hurtStartTicks = level.Ticks()
 		} else {
 			h.Kill(level)
 		}
@@ -373,19 +401,14 @@ func (h *Hero) Hurt(level *Level) {
 func (h *Hero) Kill(level *Level) {
 	h.gradeWhenDie = h.grade
 	h.downgradeToLowestSilent()
-	h.lives--
+	livesLeft := level.loseLife()
 	h.isDead = true
 	h.disabled = true
 
 	dieRes, dieRect := h.getDieEffectResAndRect()
-	afterDieDown := func() {
-		afterFadeOut := func() {
-			level.Restart()
-			h.Enable()
-		}
-		level.AddEffect(NewScreenFadeEffectEx(false, 1000, sdl.GetTicks(), afterFadeOut))
-	}
-	level.AddEffect(NewStraightDeadDownEffect(dieRes, dieRect, sdl.GetTicks(), afterDieDown))
+	afterDieDown := level.newHook(hook_hero_died_down)
+	afterDieDown.LivesLeft = livesLeft
+	level.AddEffect(NewStraightDeadDownEffect(dieRes, dieRect, level.Ticks(), afterDieDown))
 	audio.StopMusic()
 	audio.PlaySound(audio.SOUND_HERO_DIE)
 }
@@ -394,10 +417,28 @@ func (h *Hero) GetLives() int {
 	return h.lives
 }
 
+func (h *Hero) GetGrade() int {
+	return h.grade
+}
+
+// RestoreState sets hero's grade and lives directly, without any effect or sound
+func (h *Hero) RestoreState(grade int, lives int) {
+	h.downgradeToLowestSilent()
+	if grade > 0 {
+		h.grade = grade
+		h.switchResSet(grade)
+		h.reCalcLevelRectSize()
+	}
+	h.lives = lives
+}
+
 func (h *Hero) LiveAndResetPos(pos vector.Pos) {
 	h.levelRect.X = pos.X
 	h.levelRect.Y = pos.Y
+	h.subPixel = vector.Vec2D{}
 	h.isDead = false
+	h.jumpBuffer = 0
+	h.stompCombo = 0
 	h.lastFireTicks = 0
 	h.hurtStartTicks = 0
 }
@@ -538,7 +579,7 @@ func (h *Hero) upgradeTo(grade int, level *Level) {
 	h.reCalcLevelRectSize()
 
 	// show shine effects
-	level.AddEffect(NewShineEffect(h, sdl.GetTicks()))
+	level.AddEffect(NewShineEffect(h, level.Ticks()))
 
 	// play sound
 	audio.PlaySound(audio.SOUND_POWERUP)
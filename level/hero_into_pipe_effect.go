@@ -9,20 +9,20 @@ import (
 var _ Effect = &heroIntoPipeEffect{}
 
 type heroIntoPipeEffect struct {
-	res            graphic.Resource
-	levelRect      sdl.Rect
-	startTicks     uint32
-	lastTicks      uint32
-	finished       bool
-	onFinishedHook func()
+	res        graphic.Resource
+	levelRect  sdl.Rect
+	startTicks uint32
+	lastTicks  uint32
+	finished   bool
+	onFinished hook
 }
 
-func NewHeroIntoPipeEffect(h *Hero, ticks uint32, onFinished func()) *heroIntoPipeEffect {
+func NewHeroIntoPipeEffect(h *Hero, ticks uint32, onFinished hook) *heroIntoPipeEffect {
 	return &heroIntoPipeEffect{
-		res:            h.currRes,
-		levelRect:      h.getRenderRect(),
-		startTicks:     ticks,
-		onFinishedHook: onFinished,
+		res:        h.currRes,
+		levelRect:  h.getRenderRect(),
+		startTicks: ticks,
+		onFinished: onFinished,
 	}
 }
 
@@ -38,7 +38,7 @@ func (hipe *heroIntoPipeEffect) Update(ticks uint32) {
 	}
 
 	velocity := vector.Vec2D{0, 100}
-	velStep := CalcVelocityStep(velocity, ticks, hipe.lastTicks, nil)
+	velStep := CalcVelocityStep(velocity, ticks, hipe.lastTicks, nil, nil)
 	hipe.levelRect.X += velStep.X
 	hipe.levelRect.Y += velStep.Y
 	hipe.levelRect.W -= velStep.X
@@ -58,7 +58,5 @@ func (hipe *heroIntoPipeEffect) Finished() bool {
 }
 
 func (hipe *heroIntoPipeEffect) OnFinished() {
-	if hipe.onFinishedHook != nil {
-		hipe.onFinishedHook()
-	}
+	hipe.onFinished.run()
 }
@@ -0,0 +1,67 @@
+package level
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+var _ Effect = &heroOutOfPipeEffect{}
+
+// heroOutOfPipeEffect is the reverse of heroIntoPipeEffect, hero rises from the pipe below it
+type heroOutOfPipeEffect struct {
+	res        graphic.Resource
+	finalRect  sdl.Rect
+	levelRect  sdl.Rect
+	startTicks uint32
+	lastTicks  uint32
+	finished   bool
+	onFinished hook
+}
+
+func NewHeroOutOfPipeEffect(h *Hero, ticks uint32, onFinished hook) *heroOutOfPipeEffect {
+	finalRect := h.getRenderRect()
+	levelRect := finalRect
+	levelRect.Y += levelRect.H
+	levelRect.H = 0
+	return &heroOutOfPipeEffect{
+		res:        h.currRes,
+		finalRect:  finalRect,
+		levelRect:  levelRect,
+		startTicks: ticks,
+		onFinished: onFinished,
+	}
+}
+
+func (hope *heroOutOfPipeEffect) Update(ticks uint32) {
+	if hope.lastTicks == 0 {
+		hope.lastTicks = ticks
+		return
+	}
+
+	velocity := vector.Vec2D{0, -100}
+	velStep := CalcVelocityStep(velocity, ticks, hope.lastTicks, nil, nil)
+	hope.levelRect.Y += velStep.Y
+	hope.levelRect.H -= velStep.Y
+
+	if hope.levelRect.H >= hope.finalRect.H {
+		hope.levelRect = hope.finalRect
+		hope.finished = true
+	}
+
+	hope.lastTicks = ticks
+}
+
+func (hope *heroOutOfPipeEffect) Draw(camPos vector.Pos, ticks uint32) {
+	if !hope.Finished() && hope.levelRect.H > 0 {
+		graphic.DrawResource(hope.res, hope.levelRect, camPos)
+	}
+}
+
+func (hope *heroOutOfPipeEffect) Finished() bool {
+	return hope.finished
+}
+
+func (hope *heroOutOfPipeEffect) OnFinished() {
+	hope.onFinished.run()
+}
@@ -0,0 +1,55 @@
+package level
+
+import (
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+var _ Effect = &heroSlideDownEffect{}
+
+// heroSlideDownEffect slides a disabled hero down (e.g. a goal pole) until its bottom reaches bottomY
+// Hero is moved along, so that camera follows it
+type heroSlideDownEffect struct {
+	h          *Hero
+	res        graphic.Resource
+	bottomY    int32
+	lastTicks  uint32
+	subPixel   vector.Vec2D
+	finished   bool
+	onFinished hook
+}
+
+func NewHeroSlideDownEffect(h *Hero, bottomY int32, ticks uint32, onFinished hook) *heroSlideDownEffect {
+	return &heroSlideDownEffect{
+		h:          h,
+		res:        h.currResJumpRight,
+		bottomY:    bottomY,
+		lastTicks:  ticks,
+		onFinished: onFinished,
+	}
+}
+
+func (hsde *heroSlideDownEffect) Update(ticks uint32) {
+	velStep := CalcVelocityStep(vector.Vec2D{0, goal_slide_velocity}, ticks, hsde.lastTicks, nil, &hsde.subPixel)
+	hsde.h.levelRect.Y += velStep.Y
+	if hsde.h.levelRect.Y+hsde.h.levelRect.H >= hsde.bottomY {
+		hsde.h.levelRect.Y = hsde.bottomY - hsde.h.levelRect.H
+		hsde.finished = true
+	}
+
+	hsde.lastTicks = ticks
+}
+
+func (hsde *heroSlideDownEffect) Draw(camPos vector.Pos, ticks uint32) {
+	if !hsde.Finished() {
+		graphic.DrawResource(hsde.res, hsde.h.getRenderRect(), camPos)
+	}
+}
+
+func (hsde *heroSlideDownEffect) Finished() bool {
+	return hsde.finished
+}
+
+func (hsde *heroSlideDownEffect) OnFinished() {
+	hsde.onFinished.run()
+}
@@ -0,0 +1,63 @@
+package level
+
+import (
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+const (
+	walk_off_velocity    = 150
+	walk_off_duration_ms = 1500
+	walk_off_frame_ms    = 150
+)
+
+var _ Effect = &heroWalkOffEffect{}
+
+// heroWalkOffEffect walks a disabled hero to the right for a while, e.g. away from a goal pole
+// Hero is moved along, so that camera follows it
+type heroWalkOffEffect struct {
+	h          *Hero
+	reses      []graphic.Resource
+	startTicks uint32
+	lastTicks  uint32
+	subPixel   vector.Vec2D
+	finished   bool
+	onFinished hook
+}
+
+func NewHeroWalkOffEffect(h *Hero, ticks uint32, onFinished hook) *heroWalkOffEffect {
+	return &heroWalkOffEffect{
+		h:          h,
+		reses:      []graphic.Resource{h.currResWalkingRight, h.currResStandRight},
+		startTicks: ticks,
+		lastTicks:  ticks,
+		onFinished: onFinished,
+	}
+}
+
+func (hwoe *heroWalkOffEffect) Update(ticks uint32) {
+	if ticks-hwoe.startTicks > walk_off_duration_ms {
+		hwoe.finished = true
+		return
+	}
+
+	velStep := CalcVelocityStep(vector.Vec2D{walk_off_velocity, 0}, ticks, hwoe.lastTicks, nil, &hwoe.subPixel)
+	hwoe.h.levelRect.X += velStep.X
+
+	hwoe.lastTicks = ticks
+}
+
+func (hwoe *heroWalkOffEffect) Draw(camPos vector.Pos, ticks uint32) {
+	if !hwoe.Finished() {
+		res := hwoe.reses[(ticks-hwoe.startTicks)/walk_off_frame_ms%uint32(len(hwoe.reses))]
+		graphic.DrawResource(res, hwoe.h.getRenderRect(), camPos)
+	}
+}
+
+func (hwoe *heroWalkOffEffect) Finished() bool {
+	return hwoe.finished
+}
+
+func (hwoe *heroWalkOffEffect) OnFinished() {
+	hwoe.onFinished.run()
+}
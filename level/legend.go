@@ -0,0 +1,142 @@
+package level
+
+import (
+	"fmt"
+
+	"github.com/pelletier/go-toml"
+)
+
+// TileDef is what a character in level definition stands for
+// By default a character stands for the tile type using it as default character,
+// a level can change that with a [legend] table in its file, like:
+//
+//	[legend]
+//	"b" = "brick-yellow"
+//	"$" = { type = "coin-box", coins = 5 }
+type TileDef struct {
+	Type   string
+	Params map[string]interface{}
+}
+
+// DecorationGlyphs are characters of decorations in decoration definition by name, "." means none
+var DecorationGlyphs = map[string]byte{
+	"grass": '1',
+	"tree":  '2',
+}
+
+// TileAt returns the tile definition of a character in level definition, false if the character is unknown
+func (spec *LevelSpec) TileAt(x, y int) (TileDef, bool) {
+	return spec.TileOf(spec.LevelArr[y][x])
+}
+
+// TileOf returns what a character stands for in this level, false if the character is unknown
+func (spec *LevelSpec) TileOf(c byte) (TileDef, bool) {
+	if td, ok := spec.Legend[c]; ok {
+		return td, true
+	}
+	if t, ok := tileTypesByGlyph[c]; ok {
+		return TileDef{Type: t.Name}, true
+	}
+	return TileDef{}, false
+}
+
+// IntParam returns an integer param, or its default value
+func (td TileDef) IntParam(name string) int {
+	if v, ok := td.Params[name].(int64); ok {
+		return int(v)
+	}
+	return int(tileTypesByName[td.Type].Params[name].(int64))
+}
+
+// StringParam returns a string param, or its default value
+func (td TileDef) StringParam(name string) string {
+	if v, ok := td.Params[name].(string); ok {
+		return v
+	}
+	return tileTypesByName[td.Type].Params[name].(string)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// typeAt returns the tile type at the position, nil if unknown or out of level
+func (spec *LevelSpec) typeAt(x, y int) *TileType {
+	if y < 0 || y >= len(spec.LevelArr) || x < 0 || x >= len(spec.LevelArr[y]) {
+		return nil
+	}
+	td, ok := spec.TileAt(x, y)
+	if !ok {
+		return nil
+	}
+	return tileTypesByName[td.Type]
+}
+
+// getLegend reads the optional [legend] table
+func (r *specReader) getLegend() map[byte]TileDef {
+	legend := make(map[byte]TileDef)
+	if !r.conf.Has("legend") {
+		return legend
+	}
+	table, ok := r.conf.Get("legend").(*toml.Tree)
+	if !ok {
+		r.errorf("legend", "should be a table")
+		return legend
+	}
+
+	for _, key := range table.Keys() {
+		errorf := func(format string, args ...interface{}) {
+			pos := table.GetPositionPath([]string{key})
+			r.errs = append(r.errs, &ParseError{
+				File: r.file,
+				Row:  pos.Line,
+				Col:  pos.Col,
+				Msg:  fmt.Sprintf("legend %q: ", key) + fmt.Sprintf(format, args...),
+			})
+		}
+
+		if len(key) != 1 {
+			errorf("should be a single character")
+			continue
+		}
+
+		var td TileDef
+		switch v := table.GetPath([]string{key}).(type) {
+		case string:
+			td.Type = v
+		case *toml.Tree:
+			typeName, ok := v.Get("type").(string)
+			if !ok {
+				errorf("should have a type")
+				continue
+			}
+			td.Type = typeName
+			td.Params = make(map[string]interface{})
+			for _, param := range v.Keys() {
+				if param == "type" {
+					continue
+				}
+				tileType, ok := tileTypesByName[typeName]
+				if !ok {
+					// reported below
+					break
+				}
+				if err := tileType.checkParam(param, v.Get(param)); err != nil {
+					errorf("%s", err)
+					continue
+				}
+				td.Params[param] = v.Get(param)
+			}
+		default:
+			errorf("should be a type name or a table")
+			continue
+		}
+
+		if _, ok := tileTypesByName[td.Type]; !ok {
+			errorf("unknown tile type %s", td.Type)
+			continue
+		}
+		legend[key[0]] = td
+	}
+	return legend
+}
@@ -7,12 +7,16 @@ import (
 
 	"github.com/veandco/go-sdl2/sdl"
 	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/clock"
 	"github.com/zenja/mario/event"
 	"github.com/zenja/mario/graphic"
 	"github.com/zenja/mario/vector"
-	"golang.org/x/tools/container/intsets"
 )
 
+// EntityID tells an enemy or a volatile object apart from others in a level, 0 means none is given yet
+// Enemies built from the level spec always get the same IDs, in the order they appear in the spec
+type EntityID uint32
+
 type Level struct {
 	// Public
 	Spec         *LevelSpec
@@ -27,33 +31,98 @@ type Level struct {
 	TheHero      *Hero
 	InitHeroPos  vector.Pos
 	Coins        int
+	Score        int
+
+	// what is restored when hero respawns after death
+	RespawnPolicy RespawnPolicy
+
+	// points given for stomping, killing, breaking, etc.
+	ScoreTable ScoreTable
 
 	// Private
 
+	// game time source, shared with the upper game
+	clock clock.Clock
+
 	effects *list.List
 
 	// if not empty, it means we should switch to next level
 	nextLevelName string
+
+	// time left in milliseconds if the level has a time limit, see TimeLeft
+	timeLeftMs     int
+	timerLastTicks uint32
+
+	// if true, the level is completed through its goal and there is no next level to go
+	completed bool
+
+	// level time when the level is started, and how long it took to reach the goal (0 if not yet), see ClearTime
+	startTicks uint32
+	clearTicks uint32
+
+	// if true, hero has died with no lives left, see GameOver
+	gameOver bool
+
+	// the last checkpoint touched, nil if none
+	checkpoint *vector.TileID
+	// tiles of coins collected before respawns, see RespawnPolicy.ResetCoins
+	collectedCoins map[vector.TileID]bool
+
+	// game time when the level is suspended, 0 if it is not
+	suspendedTicks uint32
+	// game time spent suspended, which doesn't count as level time
+	ticksSuspended uint32
+
+	// the last entity ID given, see EntityID
+	lastEntityID EntityID
+
+	// tiles removed by RemoveObstacleTileObject, e.g. broken bricks, in the order they are removed
+	removedTiles []removedTile
+
+	// records of the last steps to go back to when rewinding, nil unless enabled, see EnableRewind
+	rewinds *rewindBuffer
+}
+
+// removedTile is a tile removed from level, kept so that it can be put back when rewinding
+type removedTile struct {
+	tid  vector.TileID
+	obj  Object
+	obst obstType
 }
 
 func (l *Level) Init() {
+	if l.startTicks == 0 {
+		l.startTicks = l.Ticks()
+	}
+	// there is nothing to rewind to when the level is started or entered
+	if l.rewinds != nil {
+		l.rewinds.clear()
+	}
 	l.fadeIn()
 	l.TheHero.LiveAndResetPos(l.InitHeroPos)
 	audio.PlayMusic()
 }
 
-func (l *Level) HandleEvents(events *intsets.Sparse) {
-	if events.Has(int(event.EVENT_KEYDOWN_F4)) {
+func (l *Level) HandleEvents(input *event.Input) {
+	if input.IsPressed(event.EVENT_KEYDOWN_F4) {
 		l.fadeIn()
 	}
 }
 
-func (l *Level) Update(events *intsets.Sparse, ticks uint32) {
+func (l *Level) Update(input *event.Input) {
+	ticks := l.Ticks()
+
 	// defensive prevention
 	if nextLevel, shouldSwitch := l.GetNextLevel(); shouldSwitch {
 		log.Fatalf("level should switch to %s, cannot update", nextLevel)
 	}
 
+	// rewinding goes back a step instead of going on
+	if l.rewinds != nil && input.IsHeld(event.EVENT_KEYDOWN_REWIND) {
+		l.rewind()
+		return
+	}
+
 	// update tile objects
 	for i := 0; i < int(l.NumTiles.X); i++ {
 		for j := 0; j < int(l.NumTiles.Y); j++ {
@@ -65,9 +134,11 @@ func (l *Level) Update(events *intsets.Sparse, ticks uint32) {
 		}
 	}
 
+	l.updateTimer(ticks)
+
 	if !l.TheHero.IsDead() {
 		// update hero with events
-		l.TheHero.HandleEvents(events, l)
+		l.TheHero.HandleEvents(input, l)
 		l.TheHero.Update(ticks, l)
 
 		// if hero is out of level, kills it
@@ -124,9 +195,16 @@ func (l *Level) Update(events *intsets.Sparse, ticks uint32) {
 	for _, e := range finishedEffs {
 		l.effects.Remove(e)
 	}
+
+	// keep the level after the step to rewind to
+	if l.rewinds != nil {
+		l.rewinds.record(l)
+	}
 }
 
-func (l *Level) Draw(camPos vector.Pos, ticks uint32) {
+func (l *Level) Draw(camPos vector.Pos) {
+	ticks := l.Ticks()
+
 	// render background
 	bgLevelRect := sdl.Rect{
 		camPos.X * 95 / 100,
@@ -206,33 +284,79 @@ func (l *Level) GetLevelHeight() int32 {
 	return l.NumTiles.Y * graphic.TILE_SIZE
 }
 
+// Ticks returns current game time of the level, time when the level is suspended is not counted
+func (l *Level) Ticks() uint32 {
+	if l.suspendedTicks != 0 {
+		return l.suspendedTicks - l.ticksSuspended
+	}
+	return l.clock.Ticks() - l.ticksSuspended
+}
+
 func (l *Level) AddEffect(e Effect) {
 	l.effects.PushFront(e)
 }
 
 func (l *Level) RemoveObstacleTileObject(tid vector.TileID) {
+	l.removedTiles = append(l.removedTiles, removedTile{
+		tid:  tid,
+		obj:  l.TileObjects[tid.X][tid.Y],
+		obst: l.ObstMngr.tileObst(tid),
+	})
 	l.TileObjects[tid.X][tid.Y] = nil
 	l.ObstMngr.RemoveTileObst(tid)
 }
 
 func (l *Level) AddVolatileObject(vo volatileObject) {
+	if vo.ID() == 0 {
+		vo.setID(l.newEntityID())
+	}
 	l.VolatileObjs.PushBack(vo)
 }
 
 func (l *Level) AddEnemy(e Enemy) {
+	if e.ID() == 0 {
+		e.setID(l.newEntityID())
+	}
 	l.Enemies = append(l.Enemies, e)
 }
 
+// Restart starts the level over from scratch, checkpoints touched are forgotten
 func (l *Level) Restart() {
+	l.checkpoint = nil
+	l.collectedCoins = make(map[vector.TileID]bool)
+	l.resetTimer()
+	l.startTicks = 0
+	l.clearTicks = 0
+
 	// reset things needs to be reset with new level
-	newLevel := BuildLevel(l.Spec)
+	newLevel, err := BuildLevel(l.Spec, l.clock)
+	if err != nil {
+		// the spec has been built once, it should never happen
+		log.Fatal(err)
+	}
 	l.TileObjects = newLevel.TileObjects
 	l.Enemies = newLevel.Enemies
 	l.ObstMngr = newLevel.ObstMngr
+	l.removedTiles = nil
 
 	l.Init()
 }
 
+// Suspend freezes the level when hero leaves it, so that it can be entered again later as it was
+func (l *Level) Suspend() {
+	if l.suspendedTicks == 0 {
+		l.suspendedTicks = l.clock.Ticks()
+	}
+}
+
+// Resume undoes Suspend, the level continues from where it was left, e.g. after the game is paused
+func (l *Level) Resume() {
+	if l.suspendedTicks != 0 {
+		l.ticksSuspended += l.clock.Ticks() - l.suspendedTicks
+		l.suspendedTicks = 0
+	}
+}
+
 func (l *Level) ShouldSwitchLevel(nextLevelName string) {
 	l.nextLevelName = nextLevelName
 }
@@ -250,8 +374,14 @@ func (l *Level) GetNextLevel() (string, bool) {
 // Private helpers
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// newEntityID gives an ID never given in the level before
+func (l *Level) newEntityID() EntityID {
+	l.lastEntityID++
+	return l.lastEntityID
+}
+
 func (l *Level) fadeIn() {
-	l.AddEffect(NewScreenFadeEffect(true, 1000, sdl.GetTicks()))
+	l.AddEffect(NewScreenFadeEffect(true, 1000, l.Ticks()))
 }
 
 func (l *Level) isOutOfLevel(rect sdl.Rect) bool {
@@ -0,0 +1,96 @@
+package level
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+const (
+	tally_duration_ms = 2000
+	tally_hold_ms     = 1500
+)
+
+var _ Effect = &levelCompleteEffect{}
+
+// levelCompleteEffect shows bonuses of completing a level on screen, counting them up into score
+// Bonuses are added to score of the level when counted up
+type levelCompleteEffect struct {
+	level       *Level
+	flagBonus   int
+	timeBonus   int
+	scoreBefore int
+	startTicks  uint32
+	counted     bool
+	finished    bool
+	onFinished  hook
+}
+
+func NewLevelCompleteEffect(level *Level, flagBonus, timeBonus int, ticks uint32, onFinished hook) *levelCompleteEffect {
+	return &levelCompleteEffect{
+		level:       level,
+		flagBonus:   flagBonus,
+		timeBonus:   timeBonus,
+		scoreBefore: level.Score,
+		startTicks:  ticks,
+		onFinished:  onFinished,
+	}
+}
+
+func (lce *levelCompleteEffect) Update(ticks uint32) {
+	// the timer runs out as time bonus is counted
+	if _, ok := lce.level.TimeLeft(); ok {
+		_, timeShown := lce.shown(ticks)
+		lce.level.timeLeftMs = (lce.timeBonus - timeShown) / time_bonus_per_second * 1000
+	}
+
+	elapsed := ticks - lce.startTicks
+	if elapsed >= tally_duration_ms && !lce.counted {
+		lce.level.Score = lce.scoreBefore + lce.flagBonus + lce.timeBonus
+		lce.counted = true
+		audio.PlaySound(audio.SOUND_COIN)
+	}
+	if elapsed >= tally_duration_ms+tally_hold_ms {
+		lce.finished = true
+	}
+}
+
+func (lce *levelCompleteEffect) Draw(camPos vector.Pos, ticks uint32) {
+	if lce.Finished() {
+		return
+	}
+
+	flagShown, timeShown := lce.shown(ticks)
+
+	white := sdl.Color{255, 255, 255, 255}
+	x := int32(graphic.SCREEN_WIDTH/2 - 120)
+	y := int32(graphic.SCREEN_HEIGHT/2 - 80)
+	graphic.DrawText("LEVEL COMPLETE", vector.Pos{x, y}, white)
+	graphic.DrawText(fmt.Sprintf("FLAG BONUS  %6d", flagShown), vector.Pos{x, y + 50}, white)
+	graphic.DrawText(fmt.Sprintf("TIME BONUS  %6d", timeShown), vector.Pos{x, y + 80}, white)
+	graphic.DrawText(fmt.Sprintf("SCORE       %6d", lce.scoreBefore+flagShown+timeShown), vector.Pos{x, y + 110}, white)
+}
+
+func (lce *levelCompleteEffect) Finished() bool {
+	return lce.finished
+}
+
+func (lce *levelCompleteEffect) OnFinished() {
+	lce.onFinished.run()
+}
+
+// shown returns how much of bonuses are counted up, flag bonus first and then time bonus
+func (lce *levelCompleteEffect) shown(ticks uint32) (flagShown, timeShown int) {
+	elapsed := int(ticks - lce.startTicks)
+	if elapsed > tally_duration_ms {
+		elapsed = tally_duration_ms
+	}
+	counted := (lce.flagBonus + lce.timeBonus) * elapsed / tally_duration_ms
+	if counted <= lce.flagBonus {
+		return counted, 0
+	}
+	return lce.flagBonus, counted - lce.flagBonus
+}
@@ -0,0 +1,189 @@
+package level_test
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestMain(m *testing.M) {
+	// asset paths are relative to the repo root
+	if err := os.Chdir(".."); err != nil {
+		log.Fatal(err)
+	}
+
+	// no window needed to run levels
+	graphic.Init(graphic.NewNullBackend())
+
+	os.Exit(m.Run())
+}
+
+func TestHeroFallsOntoGround(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"....",
+		".H..",
+		"....",
+		"BBBB",
+	), clk)
+
+	runFrames(l, clk, 60)
+
+	heroRect := l.TheHero.GetRect()
+	if heroRect.Y+heroRect.H != 3*graphic.TILE_SIZE {
+		t.Errorf("expected hero to stand on ground (bottom %d) but bottom was %d",
+			3*graphic.TILE_SIZE, heroRect.Y+heroRect.H)
+	}
+	if l.TheHero.IsDead() {
+		t.Error("expected hero to be alive")
+	}
+}
+
+func TestHeroStompsMushroomEnemy(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"BBBBB",
+		".H...",
+		".....",
+		".....",
+		".1...",
+		"BBBBB",
+	), clk)
+
+	runFrames(l, clk, 60)
+
+	if len(l.Enemies) != 1 {
+		t.Fatalf("expected 1 enemy but was %d", len(l.Enemies))
+	}
+	if !l.Enemies[0].IsDead() {
+		t.Error("expected mushroom enemy to be stomped")
+	}
+	if l.TheHero.IsDead() {
+		t.Error("expected hero to be alive")
+	}
+}
+
+func TestSuspendedLevelContinuesAsLeft(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"H.........",
+		"....1.....",
+		"BBBBBBBBBB",
+	), clk)
+	runFrames(l, clk, 20)
+
+	ticks := l.Ticks()
+	enemyX := l.Enemies[0].GetRect().X
+	l.Suspend()
+	clk.Advance(10000)
+	if l.Ticks() != ticks {
+		t.Errorf("expected level time to stop at %d when suspended but was %d", ticks, l.Ticks())
+	}
+
+	l.Resume()
+	if l.Ticks() != ticks {
+		t.Errorf("expected level time to continue from %d but was %d", ticks, l.Ticks())
+	}
+	runFrames(l, clk, 1)
+	if dx := l.Enemies[0].GetRect().X - enemyX; dx > 5 || dx < -5 {
+		t.Errorf("expected enemy to move a little after resumed but moved %d", dx)
+	}
+}
+
+func TestResumeKeepsPendingSwitch(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"H...",
+		"BBBB",
+	), clk)
+	l.Init()
+
+	l.ShouldSwitchLevel("level-1")
+	l.Suspend()
+	clk.Advance(1000)
+	l.Resume()
+	if next, ok := l.GetNextLevel(); !ok || next != "level-1" {
+		t.Errorf("expected switch to level-1 to be kept after resumed but was %q", next)
+	}
+}
+
+func TestHoldingJumpDoesNotJumpAgain(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"....",
+		"....",
+		"....",
+		"....",
+		"....",
+		".H..",
+		"BBBB",
+	), clk)
+
+	// land first
+	runFrames(l, clk, 30)
+	groundY := l.TheHero.GetRect().Y
+
+	var jump intsets.Sparse
+	jump.Insert(int(event.EVENT_KEYDOWN_SPACE))
+	tracker := event.NewTracker()
+	jumped := 0
+	inAir := false
+	for i := 0; i < 200; i++ {
+		l.Update(tracker.Next(&jump))
+		clk.Advance(level.SIMULATION_STEP_MS)
+		if y := l.TheHero.GetRect().Y; y < groundY && !inAir {
+			jumped++
+			inAir = true
+		} else if y == groundY {
+			inAir = false
+		}
+	}
+
+	if jumped != 1 {
+		t.Errorf("expected hero to jump once while jump key is held but jumped %d times", jumped)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func mustBuildLevel(t *testing.T, spec *level.LevelSpec, clk *clock.ManualClock) *level.Level {
+	l, err := level.BuildLevel(spec, clk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func newTestSpec(rows ...string) *level.LevelSpec {
+	var levelArr, decArr [][]byte
+	for _, r := range rows {
+		levelArr = append(levelArr, []byte(r))
+		decArr = append(decArr, bytes.Repeat([]byte("."), len(r)))
+	}
+	return &level.LevelSpec{
+		Name:       "test",
+		BgFilename: "assets/bg-0.png",
+		BgColor:    sdl.Color{0, 0, 0, 255},
+		LevelArr:   levelArr,
+		DecArr:     decArr,
+	}
+}
+
+// runFrames updates the level frame by frame without any input
+func runFrames(l *level.Level, clk *clock.ManualClock, n int) {
+	tracker := event.NewTracker()
+	for i := 0; i < n; i++ {
+		l.Update(tracker.Next(nil))
+		clk.Advance(level.SIMULATION_STEP_MS)
+	}
+}
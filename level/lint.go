@@ -0,0 +1,167 @@
+package level
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// all characters known in decoration definition, "-" is used like "." to mark ground rows
+const knownDecChars = ".-12"
+
+// pairs of pipe part types, the left one must be followed by the right one
+var pipePairs = map[string]string{
+	"pipe-left-mid":       "pipe-right-mid",
+	"pipe-left-mid-eater": "pipe-right-mid",
+	"level-pipe-left-top": "level-pipe-right-top",
+	"pipe-left-top":       "pipe-right-top",
+	"pipe-left-bottom":    "pipe-right-bottom",
+}
+
+// LintLevel finds problems which do not stop a valid spec from being built but are likely mistakes
+// It builds the level, so graphic must have been initialized (a null backend is enough)
+func LintLevel(spec *LevelSpec) ParseErrors {
+	var errs ParseErrors
+	errorAt := func(row, col int, format string, args ...interface{}) {
+		errs = append(errs, &ParseError{File: spec.Filename, Row: row, Col: col, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	// unknown characters
+	for y, row := range spec.LevelArr {
+		for x, c := range row {
+			if _, ok := spec.TileAt(x, y); !ok {
+				errorAt(spec.DefRow(y), x+1, "unknown tile character '%c'", c)
+			}
+		}
+	}
+	for y, row := range spec.DecArr {
+		for x, c := range row {
+			if !isKnownChar(knownDecChars, c) {
+				errorAt(spec.decDefRow(y), x+1, "unknown decoration character '%c'", c)
+			}
+		}
+	}
+
+	// unclosed pipes, checked with tile types so that legend is respected
+	for y, row := range spec.LevelArr {
+		for x, c := range row {
+			name := typeNameAt(spec, x, y)
+			if right, ok := pipePairs[name]; ok && typeNameAt(spec, x+1, y) != right {
+				errorAt(spec.DefRow(y), x+1, "pipe part '%c' is not followed by its right part", c)
+			}
+			if !isPipeRight(name) {
+				continue
+			}
+			if right, ok := pipePairs[typeNameAt(spec, x-1, y)]; !ok || right != name {
+				errorAt(spec.DefRow(y), x+1, "pipe part '%c' has no left part", c)
+			}
+		}
+	}
+
+	// entries which can't be targeted or have no pipe to come out of
+	for y, row := range spec.LevelArr {
+		for x := range row {
+			if typeNameAt(spec, x, y) != EntryTileType {
+				continue
+			}
+			if td, _ := spec.TileAt(x, y); len(td.StringParam("name")) == 0 {
+				errorAt(spec.DefRow(y), x+1, "entry has no name, name it in [legend]")
+			}
+			if below := typeNameAt(spec, x, y+1); below != "pipe-left-top" && below != "level-pipe-left-top" {
+				errorAt(spec.DefRow(y), x+1, "entry is not right above the left top of a pipe")
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		// building may not make sense
+		return errs
+	}
+
+	l, err := BuildLevel(spec, nil)
+	if err != nil {
+		errs.Add(spec.Filename, err)
+		return errs
+	}
+
+	// enemies spawned inside solid tiles
+	for _, e := range l.Enemies {
+		switch e.(type) {
+		case *mushroomEnemy, *tortoiseEnemy:
+		default:
+			// other enemies like eater flowers and level jumpers live inside pipes
+			continue
+		}
+		rect := e.GetRect()
+		// only check the body, tall enemies are allowed to overlap ground below when spawned
+		if rect.H > graphic.TILE_SIZE {
+			rect.H = graphic.TILE_SIZE
+		}
+		if tid, ok := l.ObstMngr.findSolidTile(rect, SOLVE_COLLISION_ENEMY); ok {
+			errorAt(spec.DefRow(int(rect.Y/graphic.TILE_SIZE)), int(rect.X/graphic.TILE_SIZE)+1,
+				"enemy spawned inside solid tile at row %d col %d", spec.DefRow(int(tid.Y)), tid.X+1)
+		}
+	}
+
+	return errs
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func isKnownChar(known string, c byte) bool {
+	for i := 0; i < len(known); i++ {
+		if known[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+func isPipeRight(name string) bool {
+	for _, right := range pipePairs {
+		if right == name {
+			return true
+		}
+	}
+	return false
+}
+
+// typeNameAt returns the tile type name at the position, empty if unknown or out of level
+func typeNameAt(spec *LevelSpec, x, y int) string {
+	if t := spec.typeAt(x, y); t != nil {
+		return t.Name
+	}
+	return ""
+}
+
+func (spec *LevelSpec) decDefRow(y int) int {
+	if spec.DecDefLine == 0 {
+		return y + 1
+	}
+	return spec.DecDefLine + y
+}
+
+// findSolidTile returns a tile overlapped by the rect which is an obstacle of any kind
+func (om *ObstacleManager) findSolidTile(rect sdl.Rect, sctype SolveCollisionType) (vector.TileID, bool) {
+	for x := rect.X / graphic.TILE_SIZE; x <= (rect.X+rect.W-1)/graphic.TILE_SIZE; x++ {
+		for y := rect.Y / graphic.TILE_SIZE; y <= (rect.Y+rect.H-1)/graphic.TILE_SIZE; y++ {
+			tid := vector.TileID{x, y}
+			if !om.isLegalTilePos(tid) {
+				continue
+			}
+			switch om.obsts[x][y] {
+			case normal_obst, up_thru_obst:
+				return tid, true
+			case enemy_only_obst:
+				if sctype == SOLVE_COLLISION_ENEMY {
+					return tid, true
+				}
+			}
+		}
+	}
+	return vector.TileID{}, false
+}
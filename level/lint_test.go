@@ -0,0 +1,51 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/level"
+)
+
+func TestLintLevel(t *testing.T) {
+	spec := newTestSpec(
+		".H....",
+		"..[.X.",
+		"BBBB]B",
+	)
+
+	errs := level.LintLevel(spec)
+
+	expected := []struct {
+		row, col int
+	}{
+		{2, 3}, // '[' not followed by ']'
+		{2, 5}, // unknown 'X'
+		{3, 5}, // ']' without left part
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d problems but was:\n%s", len(expected), errs)
+	}
+	for _, e := range expected {
+		found := false
+		for _, err := range errs {
+			if err.Row == e.row && err.Col == e.col {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected problem at %d:%d but was:\n%s", e.row, e.col, errs)
+		}
+	}
+}
+
+func TestLintShippedLevels(t *testing.T) {
+	specs, err := level.LoadLevelSpecs(levelsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range specs {
+		if errs := level.LintLevel(spec); len(errs) > 0 {
+			t.Errorf("level %s has problems:\n%s", spec.Name, errs)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package level
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/vector"
+)
+
+// All rules of how hero gains and loses lives are here
+
+const (
+	// lives hero has when a game starts or continues
+	INIT_LIVES = 3
+
+	// hero never has more lives than this
+	MAX_LIVES = 99
+
+	// every this many coins collected gives an extra life
+	COINS_PER_LIFE = 100
+)
+
+// GameOver tells if hero has died with no lives left, the upper game decides how to go on
+func (l *Level) GameOver() bool {
+	return l.gameOver
+}
+
+// Continue starts the level over after game over, with lives refilled but score lost
+func (l *Level) Continue() {
+	l.TheHero.lives = INIT_LIVES
+	l.Score = 0
+	l.Coins = 0
+	l.gameOver = false
+	l.Restart()
+	l.TheHero.Enable()
+}
+
+// AddCoin counts a coin collected, every COINS_PER_LIFE coins are turned into an extra life
+func (l *Level) AddCoin(rect sdl.Rect) {
+	l.Coins++
+	if l.Coins >= COINS_PER_LIFE {
+		l.Coins -= COINS_PER_LIFE
+		l.AddLife(rect)
+	}
+}
+
+// AddLife gives hero an extra life, unless hero has already got MAX_LIVES
+func (l *Level) AddLife(rect sdl.Rect) {
+	if l.TheHero.lives >= MAX_LIVES {
+		return
+	}
+	l.TheHero.lives++
+	l.AddEffect(NewTextPopupEffect("1UP", vector.Pos{rect.X + rect.W/2, rect.Y}, l.Ticks()))
+	audio.PlaySound(audio.SOUND_POWERUP)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// loseLife takes a life when hero dies, and returns false if it was the last one
+func (l *Level) loseLife() bool {
+	if l.TheHero.lives > 0 {
+		l.TheHero.lives--
+	}
+	return l.TheHero.lives > 0
+}
+
+// afterHeroDied goes on when hero has died down and the screen has faded out
+func (l *Level) afterHeroDied(livesLeft bool) {
+	if !livesLeft {
+		// hero stays dead until the upper game continues or restarts
+		l.gameOver = true
+		return
+	}
+	l.Respawn()
+	l.TheHero.Enable()
+}
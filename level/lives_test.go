@@ -0,0 +1,59 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/level"
+)
+
+func TestGameOverAndContinue(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		".H........",
+		"BBBBBBBBBB",
+	), clk)
+	l.Init()
+	l.TheHero.RestoreState(0, 1)
+	l.Score = 1000
+
+	l.TheHero.Kill(l)
+	runFrames(l, clk, 500)
+	if !l.GameOver() || !l.TheHero.IsDead() || l.TheHero.GetLives() != 0 {
+		t.Fatalf("expected game over with hero dead, lives were %d", l.TheHero.GetLives())
+	}
+
+	l.Continue()
+	runFrames(l, clk, 100)
+	if l.GameOver() || l.TheHero.IsDead() {
+		t.Errorf("expected hero to play again after continue")
+	}
+	if l.TheHero.GetLives() != level.INIT_LIVES || l.Score != 0 {
+		t.Errorf("expected lives refilled and score lost, but lives were %d and score was %d",
+			l.TheHero.GetLives(), l.Score)
+	}
+}
+
+func TestExtraLives(t *testing.T) {
+	l := mustBuildLevel(t, newTestSpec(
+		".H........",
+		"BBBBBBBBBB",
+	), clock.NewManualClock(1))
+	l.Init()
+	rect := l.TheHero.GetRect()
+
+	for i := 0; i < level.COINS_PER_LIFE; i++ {
+		l.AddCoin(rect)
+	}
+	if l.TheHero.GetLives() != level.INIT_LIVES+1 || l.Coins != 0 {
+		t.Errorf("expected coins turned into a life, but lives were %d and coins were %d",
+			l.TheHero.GetLives(), l.Coins)
+	}
+
+	for i := 0; i < level.MAX_LIVES+10; i++ {
+		l.AddLife(rect)
+	}
+	if l.TheHero.GetLives() != level.MAX_LIVES {
+		t.Errorf("expected lives capped at %d but was %d", level.MAX_LIVES, l.TheHero.GetLives())
+	}
+}
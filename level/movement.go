@@ -1,35 +1,65 @@
 package level
 
 import (
-	"log"
-
 	mutils "github.com/zenja/mario/math_utils"
 	"github.com/zenja/mario/vector"
 )
 
-func CalcVelocityStep(velocity vector.Vec2D, currTicks uint32, lastTicks uint32, maxVel *vector.Vec2D) vector.Vec2D {
-	// calculate velocity step
-	velocityStep := velocity
-	velocityStep.Multiply(int32(currTicks - lastTicks))
-	velocityStep.Divide(1000)
+// SIMULATION_STEP_MS is the fixed time step the level is updated with
+// The upper game should always advance the level's clock by this amount between two updates
+const SIMULATION_STEP_MS = 16
+
+// sub-pixel unit: there are 1000 sub-pixels in a pixel
+// velocities are pixels per second and time is ms, so velocity * ms is exactly in sub-pixels
+const subPixelsPerPixel = 1000
+
+// CalcVelocityStep calculates how many whole pixels to move for a velocity (pixels per second) in a period of time
+// If subPixel is not nil, it keeps the sub-pixel part of the position across calls (fixed-point),
+// so that small velocities won't be rounded to zero
+// If maxVel is not nil, the step is limited to it, it works as a terminal velocity per simulation step
+func CalcVelocityStep(
+	velocity vector.Vec2D,
+	currTicks uint32,
+	lastTicks uint32,
+	maxVel *vector.Vec2D,
+	subPixel *vector.Vec2D) vector.Vec2D {
+
+	// calculate movement in sub-pixels
+	moved := velocity
+	moved.Multiply(int32(currTicks - lastTicks))
+	if subPixel != nil {
+		moved.Add(*subPixel)
+	}
+
+	// whole pixels to move, and keep the rest
+	velocityStep := moved
+	velocityStep.Divide(subPixelsPerPixel)
+	if subPixel != nil {
+		subPixel.X = moved.X - velocityStep.X*subPixelsPerPixel
+		subPixel.Y = moved.Y - velocityStep.Y*subPixelsPerPixel
+	}
 
 	// limit max velocity step if there is a limit (non-nil maxVel)
 	if maxVel != nil {
 		if mutils.Abs(velocityStep.X) > maxVel.X {
-			log.Printf("warning: velocity step's |X| is %d > %d, limited to %d", velocityStep.X, maxVel.X, maxVel.X)
 			if velocityStep.X > 0 {
 				velocityStep.X = maxVel.X
 			} else {
 				velocityStep.X = -maxVel.X
 			}
+			if subPixel != nil {
+				subPixel.X = 0
+			}
 		}
 		if mutils.Abs(velocityStep.Y) > maxVel.Y {
-			log.Printf("warning: velocity step's |Y| is %d > %d, limited to %d", velocityStep.Y, maxVel.Y, maxVel.Y)
 			if velocityStep.Y > 0 {
 				velocityStep.Y = maxVel.Y
 			} else {
 				velocityStep.Y = -maxVel.Y
 			}
+			if subPixel != nil {
+				subPixel.Y = 0
+			}
 		}
 	}
 
@@ -0,0 +1,39 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/vector"
+)
+
+func TestCalcVelocityStepKeepsSubPixels(t *testing.T) {
+	// 10 pixels per second is less than a pixel per step
+	velocity := vector.Vec2D{10, -10}
+	var subPixel vector.Vec2D
+	var moved vector.Vec2D
+	var ticks uint32 = 1
+	for i := 0; i < 1000/level.SIMULATION_STEP_MS; i++ {
+		step := level.CalcVelocityStep(velocity, ticks+level.SIMULATION_STEP_MS, ticks, nil, &subPixel)
+		moved.Add(step)
+		ticks += level.SIMULATION_STEP_MS
+	}
+
+	// 62 steps * 16ms = 992ms
+	expected := vector.Vec2D{9, -9}
+	if moved != expected {
+		t.Errorf("expected to move %v but was %v", expected, moved)
+	}
+}
+
+func TestCalcVelocityStepMaxVel(t *testing.T) {
+	maxVel := vector.Vec2D{15, 15}
+	var subPixel vector.Vec2D
+	step := level.CalcVelocityStep(vector.Vec2D{-2000, 2000}, 17, 1, &maxVel, &subPixel)
+	if step != (vector.Vec2D{-15, 15}) {
+		t.Errorf("expected step {-15, 15} but was %v", step)
+	}
+	if subPixel != (vector.Vec2D{}) {
+		t.Errorf("expected sub-pixels to be dropped when limited but was %v", subPixel)
+	}
+}
@@ -27,6 +27,7 @@ type mythBox struct {
 	isBounding bool
 	isEmpty    bool
 	velocity   vector.Vec2D
+	subPixel   vector.Vec2D
 	lastTicks  uint32
 }
 
@@ -53,7 +54,7 @@ func (ca *coinActor) onEffectiveBottomHit(mb *mythBox, level *Level, ticks uint3
 		level.AddEffect(NewCoinEffect(vector.TileID{mbTID.X, mbTID.Y - 1}, ticks))
 
 		// increase #coins
-		level.Coins++
+		level.AddCoin(mb.tileRect)
 
 		// play sound
 		audio.PlaySound(audio.SOUND_COIN)
@@ -106,6 +107,29 @@ func NewMushroomMythBox(startPos vector.Pos) *mythBox {
 	return newMythBox(startPos, &actor)
 }
 
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// 1-up actor
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+var _ mythBoxActor = &oneUpActor{}
+
+type oneUpActor struct {
+	mushroom *goodMushroom
+}
+
+func (oa *oneUpActor) onEffectiveBottomHit(mb *mythBox, level *Level, ticks uint32) {
+	level.AddEnemy(oa.mushroom)
+}
+
+func (oa *oneUpActor) onBoundingFinished(mb *mythBox, level *Level, ticks uint32) {
+	mb.Empty()
+}
+
+func NewOneUpMythBox(startPos vector.Pos) *mythBox {
+	actor := oneUpActor{mushroom: NewOneUpMushroom(startPos)}
+	return newMythBox(startPos, &actor)
+}
+
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 // Myth box methods
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -150,7 +174,7 @@ func (mb *mythBox) Update(ticks uint32, level *Level) {
 		gravity := vector.Vec2D{0, 10}
 		mb.velocity.Add(gravity)
 
-		velocityStep := CalcVelocityStep(mb.velocity, ticks, mb.lastTicks, nil)
+		velocityStep := CalcVelocityStep(mb.velocity, ticks, mb.lastTicks, nil, &mb.subPixel)
 
 		// apply velocity step
 		mb.levelRect.X += velocityStep.X
@@ -159,6 +183,7 @@ func (mb *mythBox) Update(ticks uint32, level *Level) {
 		// if reach origin (Y) position, the bounding is stopped
 		if mb.levelRect.Y >= mb.tileRect.Y {
 			mb.levelRect.Y = mb.tileRect.Y
+			mb.subPixel = vector.Vec2D{}
 			mb.isBounding = false
 			mb.actor.onBoundingFinished(mb, level, ticks)
 		}
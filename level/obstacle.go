@@ -69,6 +69,17 @@ func (om *ObstacleManager) RemoveTileObst(tileID vector.TileID) {
 	om.obsts[tileID.X][tileID.Y] = not_obst
 }
 
+// tileObst returns the obstacle type of a tile, so that it can be set back by setTileObst after the tile is removed
+func (om *ObstacleManager) tileObst(tileID vector.TileID) obstType {
+	om.assertLegalTilePos(tileID)
+	return om.obsts[tileID.X][tileID.Y]
+}
+
+func (om *ObstacleManager) setTileObst(tileID vector.TileID, t obstType) {
+	om.assertLegalTilePos(tileID)
+	om.obsts[tileID.X][tileID.Y] = t
+}
+
 func (om *ObstacleManager) SolveCollision(desiredRect *sdl.Rect, sctype SolveCollisionType) (
 	hitTop bool,
 	hitRight bool,
@@ -0,0 +1,68 @@
+package level
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// ParseError is a problem found in a level file
+// Row and Col are 1-based positions in the file, 0 means unknown
+type ParseError struct {
+	File string
+	Row  int
+	Col  int
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	var buf bytes.Buffer
+	if len(e.File) > 0 {
+		buf.WriteString(e.File)
+	} else {
+		buf.WriteString("<level>")
+	}
+	if e.Row > 0 {
+		fmt.Fprintf(&buf, ":%d", e.Row)
+		if e.Col > 0 {
+			fmt.Fprintf(&buf, ":%d", e.Col)
+		}
+	}
+	buf.WriteString(": ")
+	buf.WriteString(e.Msg)
+	return buf.String()
+}
+
+// ParseErrors is a list of problems, e.g. all problems found in one level or in all levels
+type ParseErrors []*ParseError
+
+func (errs ParseErrors) Error() string {
+	var buf bytes.Buffer
+	for i, e := range errs {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(e.Error())
+	}
+	return buf.String()
+}
+
+// Add appends an error, it flattens ParseErrors and keeps position of ParseError
+func (errs *ParseErrors) Add(file string, err error) {
+	switch e := err.(type) {
+	case nil:
+	case ParseErrors:
+		*errs = append(*errs, e...)
+	case *ParseError:
+		*errs = append(*errs, e)
+	default:
+		*errs = append(*errs, &ParseError{File: file, Msg: err.Error()})
+	}
+}
+
+// ErrOrNil returns nil if there is no error, so that an empty list is never returned as a non-nil error
+func (errs ParseErrors) ErrOrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
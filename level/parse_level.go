@@ -2,7 +2,9 @@ package level
 
 import (
 	"container/list"
-	"log"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 
 	"strings"
 
@@ -11,6 +13,7 @@ import (
 	"github.com/pelletier/go-toml"
 	"github.com/pkg/errors"
 	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/clock"
 	"github.com/zenja/mario/graphic"
 	"github.com/zenja/mario/vector"
 )
@@ -18,14 +21,51 @@ import (
 type LevelSpec struct {
 	Name           string
 	NextLevelNames []string
-	BgFilename     string // file name of background file
-	BgColor        sdl.Color
-	LevelArr       [][]byte
-	DecArr         [][]byte // decoration array
+
+	// seconds hero has to complete the level before being killed, 0 if there is no time limit
+	TimeLimit int
+
+	// if true, the level is a secret to be found, e.g. a bonus room under a pipe
+	Secret bool
+
+	// where to go after the goal is reached, like a next level; empty if the campaign is completed there
+	GoalLevelName string
+
+	// if true, the level keeps its state (broken bricks, collected coins, killed enemies...) when hero leaves
+	// and comes back, otherwise it is built again on each entry
+	Persistent bool
+
+	BgFilename string // file name of background file
+	BgColor    sdl.Color
+	LevelArr   [][]byte
+	DecArr     [][]byte // decoration array
+
+	// characters in LevelArr which do not mean their defaults, see TileAt
+	Legend map[byte]TileDef
+
+	// where the spec comes from, used in error messages; empty if not from a file
+	Filename   string
+	DefLine    int // line in file of the first row of LevelArr
+	DecDefLine int // line in file of the first row of DecArr
+}
+
+// background currently loaded as graphic.RESOURCE_TYPE_CURR_BG
+var loadedBg struct {
+	filename string
+	tilesInY int
 }
 
-func BuildLevel(spec *LevelSpec) *Level {
-	graphic.RegisterBackgroundResource(spec.BgFilename, graphic.RESOURCE_TYPE_CURR_BG, len(spec.LevelArr))
+func BuildLevel(spec *LevelSpec, clk clock.Clock) (*Level, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	// loading background is slow and levels are rebuilt often (restart, editor), so only reload it when changed
+	if loadedBg.filename != spec.BgFilename || loadedBg.tilesInY != len(spec.LevelArr) {
+		graphic.RegisterBackgroundResource(spec.BgFilename, graphic.RESOURCE_TYPE_CURR_BG, len(spec.LevelArr))
+		loadedBg.filename = spec.BgFilename
+		loadedBg.tilesInY = len(spec.LevelArr)
+	}
 	bgRes := graphic.Res(graphic.RESOURCE_TYPE_CURR_BG)
 
 	// NOTE: index is tid.X, tid.Y
@@ -42,47 +82,12 @@ func BuildLevel(spec *LevelSpec) *Level {
 		tileObjs = append(tileObjs, make([]Object, numTiles.Y))
 	}
 
-	addAsNormalObstTile := func(tid vector.TileID, o Object) {
-		tileObjs[tid.X][tid.Y] = o
-		obstMngr.AddNormalTileObst(tid)
-	}
-
-	addAsEnemyOnlyObstTile := func(tid vector.TileID, o Object) {
-		tileObjs[tid.X][tid.Y] = o
-		obstMngr.AddEnemyOnlyTileObst(tid)
-	}
-
-	addAsUpThruObstTile := func(tid vector.TileID, o Object) {
-		tileObjs[tid.X][tid.Y] = o
-		obstMngr.AddUpThruTileObst(tid)
-	}
-
-	addAsNoObstTile := func(tid vector.TileID, o Object) {
-		tileObjs[tid.X][tid.Y] = o
-	}
-
-	needAddGroundLeft := func(tid vector.TileID) bool {
-		leftSpec := spec.LevelArr[tid.Y][tid.X-1]
-		if tid.X-1 > 0 && (leftSpec == 'l' || leftSpec == 'L' || leftSpec == 'g') {
-			return true
-		}
-		return false
-	}
-
-	needAddGroundRight := func(tid vector.TileID) bool {
-		rightSpec := spec.LevelArr[tid.Y][tid.X+1]
-		if tid.X+1 < numTiles.X && (rightSpec == 'r' || rightSpec == 'R' || rightSpec == 'g') {
-			return true
-		}
-		return false
-	}
-
 	var decorations []Object
 	addDecoration := func(d *decoration) {
 		decorations = append(decorations, d)
 	}
 
-	// parse level
+	// parse level, each tile is built by its tile type
 	var currentPos vector.Pos
 	var nextLevelJumperIdx int = 0
 	for tidY := 0; tidY < int(numTiles.Y); tidY++ {
@@ -90,193 +95,41 @@ func BuildLevel(spec *LevelSpec) *Level {
 		for tidX := 0; tidX < int(numTiles.X); tidX++ {
 			tid := vector.TileID{int32(tidX), int32(tidY)}
 			// note that levelArr's index is not TID, need reverse
-			switch spec.LevelArr[tidY][tidX] {
-			// Invisible block
-			case '#':
-				addAsNormalObstTile(tid, NewInvisibleTileObject(tid))
-
-			// Invisible block only to enemies
-			case '"':
-				addAsEnemyOnlyObstTile(tid, NewInvisibleTileObject(tid))
-
-			// Brick: yellow
-			case 'B':
-				mainRes := graphic.Res(graphic.RESOURCE_TYPE_BRICK_YELLOW)
-				pieceRes := graphic.Res(graphic.RESOURCE_TYPE_BRICK_PIECE_YELLOW)
-				o := NewBreakableTileObject(mainRes, pieceRes, currentPos, ZINDEX_0)
-				addAsNormalObstTile(tid, o)
-
-			// Brick: red
-			case 'D':
-				mainRes := graphic.Res(graphic.RESOURCE_TYPE_BRICK_RED)
-				pieceRes := graphic.Res(graphic.RESOURCE_TYPE_BRICK_PIECE_RED)
-				o := NewBreakableTileObject(mainRes, pieceRes, currentPos, ZINDEX_0)
-				addAsNormalObstTile(tid, o)
-
-			// Ground with left grass
-			case 'L':
-				resID := graphic.RESOURCE_TYPE_GRASS_GROUD_LEFT
-				res := graphic.Res(resID)
-				var o Object
-				if needAddGroundLeft(tid) {
-					o = NewOverlapTilesObject(
-						[]graphic.ResourceID{graphic.RESOURCE_TYPE_GROUD_MID, resID}, tid, ZINDEX_1)
-				} else {
-					o = NewSingleTileObject(res, tid, ZINDEX_1)
-				}
-				addAsUpThruObstTile(tid, o)
-
-			// Ground with mid grass
-			case 'G':
-				res := graphic.Res(graphic.RESOURCE_TYPE_GRASS_GROUD_MID)
-				o := NewSingleTileObject(res, tid, ZINDEX_0)
-				addAsUpThruObstTile(tid, o)
-
-			// Ground with right grass
-			case 'R':
-				resID := graphic.RESOURCE_TYPE_GRASS_GROUD_RIGHT
-				res := graphic.Res(resID)
-				var o Object
-				if needAddGroundRight(tid) {
-					o = NewOverlapTilesObject(
-						[]graphic.ResourceID{graphic.RESOURCE_TYPE_GROUD_MID, resID}, tid, ZINDEX_1)
-				} else {
-					o = NewSingleTileObject(res, tid, ZINDEX_1)
-				}
-				addAsUpThruObstTile(tid, o)
-
-			// Inner ground in left
-			case 'l':
-				resID := graphic.RESOURCE_TYPE_GROUD_LEFT
-				res := graphic.Res(resID)
-				var o Object
-				if needAddGroundLeft(tid) {
-					o = NewOverlapTilesObject(
-						[]graphic.ResourceID{graphic.RESOURCE_TYPE_GROUD_MID, resID}, tid, ZINDEX_1)
-				} else {
-					o = NewSingleTileObject(res, tid, ZINDEX_1)
-				}
-				addAsNoObstTile(tid, o)
-
-			// Inner ground in middle
-			case 'g':
-				res := graphic.Res(graphic.RESOURCE_TYPE_GROUD_MID)
-				o := NewSingleTileObject(res, tid, ZINDEX_0)
-				addAsNoObstTile(tid, o)
-
-			// Inner ground in right
-			case 'r':
-				resID := graphic.RESOURCE_TYPE_GROUD_RIGHT
-				res := graphic.Res(resID)
-				var o Object
-				if needAddGroundRight(tid) {
-					o = NewOverlapTilesObject(
-						[]graphic.ResourceID{graphic.RESOURCE_TYPE_GROUD_MID, resID}, tid, ZINDEX_1)
-				} else {
-					o = NewSingleTileObject(res, tid, ZINDEX_1)
-				}
-				addAsNoObstTile(tid, o)
-
-			// Myth box for coins
-			case 'C':
-				addAsNormalObstTile(tid, NewCoinMythBox(currentPos, 3))
-
-			// Myth box for mushrooms
-			case 'M':
-				addAsNormalObstTile(tid, NewMushroomMythBox(currentPos))
-
-			// left middle of pipe
-			case '[':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_MID)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// right middle of pipe
-			case ']':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_RIGHT_MID)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// right middle of pipe, with eater flower
-			case 'E':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_MID)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-				// add eater
-				enemies = append(enemies, NewEaterFlower(tid))
-
-			// left top of pipe that will jump level
-			case '{':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_TOP)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-				// also needs to add level jumper
-				nextLevelName := spec.NextLevelNames[nextLevelJumperIdx]
-				enemies = append(enemies, NewLevelJumper(tid, nextLevelName))
+			td, ok := spec.TileAt(tidX, tidY)
+			if !ok {
+				// unknown characters are taken as empty
+				currentPos.X += graphic.TILE_SIZE
+				continue
+			}
+			tileType := tileTypesByName[td.Type]
+			ctx := &TileContext{TID: tid, Pos: currentPos, Def: td, spec: spec}
+
+			if tileType.JumpsLevel {
+				ctx.NextLevelName = spec.NextLevelNames[nextLevelJumperIdx]
 				nextLevelJumperIdx++
+			}
 
-			// right top of pipe that will jump level
-			case '}':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_RIGHT_TOP)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// left top of normal pipe
-			case '(':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_TOP)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// right top of normal pipe
-			case ')':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_RIGHT_TOP)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// left bottom of pipe
-			case '<':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_BOTTOM)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// right bottom of pipe
-			case '>':
-				res := graphic.Res(graphic.RESOURCE_TYPE_PIPE_RIGHT_BOTTOM)
-				o := NewSingleTileObject(res, tid, ZINDEX_4)
-				addAsNormalObstTile(tid, o)
-
-			// water surface
-			case 'W':
-				o := NewWaterSurfaceAnimationObject(tid)
-				addAsNoObstTile(tid, o)
-
-			// water inside
-			case 'w':
-				res := graphic.Res(graphic.RESOURCE_TYPE_WATER_FULL)
-				o := NewSingleTileObject(res, tid, ZINDEX_1)
-				addAsNoObstTile(tid, o)
-
-			// coin
-			case 'c':
-				enemies = append(enemies, NewCoinEnemy(tid))
-
-			// Enemy 1: mushroom enemy
-			case '1':
-				enemies = append(enemies, NewMushroomEnemy(currentPos))
+			if tileType.NewTile != nil {
+				tileObjs[tid.X][tid.Y] = tileType.NewTile(ctx)
+			}
+			switch tileType.Obst {
+			case normal_obst:
+				obstMngr.AddNormalTileObst(tid)
+			case enemy_only_obst:
+				obstMngr.AddEnemyOnlyTileObst(tid)
+			case up_thru_obst:
+				obstMngr.AddUpThruTileObst(tid)
+			}
 
-			// Enemy 2: tortoise enemy
-			case '2':
-				enemies = append(enemies, NewTortoiseEnemy(currentPos))
+			if tileType.NewEnemy != nil {
+				enemies = append(enemies, tileType.NewEnemy(ctx))
+			}
 
-			// Hero
-			case 'H':
-				if hero != nil {
-					log.Fatal("more than one hero found")
-				}
+			// there is exactly one hero as validated
+			if tileType.Name == HeroTileType {
 				hero = NewHero(currentPos, 0.2, 0.2)
 			}
+
 			currentPos.X += graphic.TILE_SIZE
 		}
 		currentPos.Y += graphic.TILE_SIZE
@@ -305,70 +158,227 @@ func BuildLevel(spec *LevelSpec) *Level {
 		}
 	}
 
-	if hero == nil {
-		log.Fatal("no hero found when parsing level")
+	l := &Level{
+		Spec:           spec,
+		BGRes:          bgRes,
+		Decorations:    decorations,
+		TileObjects:    tileObjs,
+		Enemies:        enemies,
+		VolatileObjs:   list.New(),
+		ObstMngr:       obstMngr,
+		TheHero:        hero,
+		InitHeroPos:    vector.Pos{hero.levelRect.X, hero.levelRect.Y},
+		BGColor:        spec.BgColor,
+		NumTiles:       numTiles,
+		RespawnPolicy:  DefaultRespawnPolicy,
+		ScoreTable:     DefaultScoreTable,
+		timeLeftMs:     spec.TimeLimit * 1000,
+		clock:          clk,
+		effects:        list.New(),
+		collectedCoins: make(map[vector.TileID]bool),
 	}
-
-	return &Level{
-		Spec:         spec,
-		BGRes:        bgRes,
-		Decorations:  decorations,
-		TileObjects:  tileObjs,
-		Enemies:      enemies,
-		VolatileObjs: list.New(),
-		ObstMngr:     obstMngr,
-		TheHero:      hero,
-		InitHeroPos:  vector.Pos{hero.levelRect.X, hero.levelRect.Y},
-		BGColor:      spec.BgColor,
-		NumTiles:     numTiles,
-		effects:      list.New(),
+	for _, e := range enemies {
+		e.setID(l.newEntityID())
 	}
+	return l, nil
 }
 
-func ParseLevelSpec(levelFile string) *LevelSpec {
+// ParseLevelSpec parses a level file, the returned error is ParseErrors which holds all problems found
+func ParseLevelSpec(levelFile string) (*LevelSpec, error) {
 	conf, err := toml.LoadFile(levelFile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, ParseErrors{&ParseError{File: levelFile, Msg: err.Error()}}
 	}
 
-	name := conf.Get("basic.name").(string)
-	var nextLevelNames []string
-	for _, name := range conf.Get("transfer.next-levels").([]interface{}) {
-		nextLevelNames = append(nextLevelNames, name.(string))
-	}
+	r := &specReader{file: levelFile, conf: conf}
 
-	bgFilename := conf.Get("graphic.bg-file").(string)
-
-	bgColor, err := parseRGB(conf.Get("graphic.bg-color-rgb").(string))
-	if err != nil {
-		log.Fatal(err)
+	name := r.getString("basic.name")
+	nextLevelNames := r.getStringArray("transfer.next-levels")
+	timeLimit := r.getOptionalInt("basic.time-limit")
+	if timeLimit < 0 {
+		r.errorf("basic.time-limit", "should not be negative")
 	}
-
-	levelDef, err := parseLevelArr(conf.Get("level.def").(string))
-	if err != nil {
-		log.Fatal(err)
+	secret := r.getOptionalBool("basic.secret")
+	goalLevelName := r.getOptionalString("transfer.goal-level")
+	persistent := r.getOptionalBool("transfer.persistent")
+	bgFilename := r.getString("graphic.bg-file")
+
+	var bgColor sdl.Color
+	if rgb := r.getString("graphic.bg-color-rgb"); len(rgb) > 0 {
+		bgColor, err = parseRGB(rgb)
+		if err != nil {
+			r.errorf("graphic.bg-color-rgb", "%s", err)
+		}
 	}
 
-	levelDecDef, err := parseLevelArr(conf.Get("level.dec-def").(string))
-	if err != nil {
-		log.Fatal(err)
-	}
+	levelDef, defLine := r.getLevelArr("level.def")
+	levelDecDef, decDefLine := r.getLevelArr("level.dec-def")
+	legend := r.getLegend()
 
-	// check if num of next levels equals to '{' in level definition
-	leftBracketCnt := strings.Count(conf.Get("level.def").(string), "{")
-	if leftBracketCnt != len(nextLevelNames) {
-		log.Fatalf("failed to parse level %s: there are %d next levels but %d '{'",
-			name, len(nextLevelNames), leftBracketCnt)
+	if len(r.errs) > 0 {
+		return nil, r.errs
 	}
 
-	return &LevelSpec{
+	spec := &LevelSpec{
 		Name:           name,
 		NextLevelNames: nextLevelNames,
+		TimeLimit:      timeLimit,
+		Secret:         secret,
+		GoalLevelName:  goalLevelName,
+		Persistent:     persistent,
 		BgFilename:     bgFilename,
 		BgColor:        bgColor,
 		LevelArr:       levelDef,
 		DecArr:         levelDecDef,
+		Legend:         legend,
+		Filename:       levelFile,
+		DefLine:        defLine,
+		DecDefLine:     decDefLine,
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// ParseLevelFile parses a level file with the parser of its extension: ParseTiledLevelSpec for Tiled maps
+// (.tmx and .tmj), ParseLevelSpec for others
+func ParseLevelFile(levelFile string) (*LevelSpec, error) {
+	switch strings.ToLower(filepath.Ext(levelFile)) {
+	case ".tmx", ".tmj":
+		return ParseTiledLevelSpec(levelFile)
+	}
+	return ParseLevelSpec(levelFile)
+}
+
+// LoadLevelSpecs parses all level files in a directory with ParseLevelFile, the key of the returned map is level name
+// The returned error is ParseErrors which holds problems of all levels, including cross-level problems
+// like duplicated level names and next levels not found; levels without problems are returned even if
+// there is an error
+func LoadLevelSpecs(dir string) (map[string]*LevelSpec, error) {
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read level dir %s", dir)
+	}
+
+	var errs ParseErrors
+	specs := make(map[string]*LevelSpec)
+	for _, info := range fileInfos {
+		if info.IsDir() || isTiledTileset(info.Name()) {
+			continue
+		}
+		filename := filepath.Join(dir, info.Name())
+		spec, err := ParseLevelFile(filename)
+		if err != nil {
+			errs.Add(filename, err)
+			continue
+		}
+		if existing, ok := specs[spec.Name]; ok {
+			errs = append(errs, &ParseError{File: filename,
+				Msg: fmt.Sprintf("level %s already defined in %s", spec.Name, existing.Filename)})
+			continue
+		}
+		specs[spec.Name] = spec
+	}
+
+	// check if the next levels of each actually exist
+	for _, spec := range specs {
+		targets := spec.NextLevelNames
+		if len(spec.GoalLevelName) > 0 {
+			targets = append(targets[:len(targets):len(targets)], spec.GoalLevelName)
+		}
+		for _, target := range targets {
+			nextLevel, entry := ParseWarpTarget(target)
+			next, ok := specs[nextLevel]
+			if !ok {
+				errs = append(errs, &ParseError{File: spec.Filename,
+					Msg: fmt.Sprintf("next level %s not found", nextLevel)})
+				continue
+			}
+			if _, ok := next.EntryTile(entry); len(entry) > 0 && !ok {
+				errs = append(errs, &ParseError{File: spec.Filename,
+					Msg: fmt.Sprintf("entry %s not found in next level %s", entry, nextLevel)})
+			}
+		}
 	}
+
+	return specs, errs.ErrOrNil()
+}
+
+// Validate checks problems which make a level spec impossible to build
+func (spec *LevelSpec) Validate() error {
+	var errs ParseErrors
+	errorAt := func(row, col int, format string, args ...interface{}) {
+		errs = append(errs, &ParseError{File: spec.Filename, Row: row, Col: col, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if len(spec.LevelArr) == 0 || len(spec.LevelArr[0]) == 0 {
+		errorAt(spec.DefLine, 0, "level definition is empty")
+		return errs
+	}
+
+	// decorations should cover exactly the same area
+	if len(spec.DecArr) != len(spec.LevelArr) || decWidth(spec.DecArr) != len(spec.LevelArr[0]) {
+		errorAt(spec.DecDefLine, 0, "decoration definition is %dx%d but level definition is %dx%d",
+			decWidth(spec.DecArr), len(spec.DecArr), len(spec.LevelArr[0]), len(spec.LevelArr))
+	}
+
+	numHeroes := 0
+	numJumpers := 0
+	entries := make(map[string]bool)
+	for y, row := range spec.LevelArr {
+		for x := range row {
+			// unknown characters are taken as empty, mario-lint reports them
+			t := spec.typeAt(x, y)
+			if t == nil {
+				continue
+			}
+			if t.Name == HeroTileType {
+				numHeroes++
+				if numHeroes > 1 {
+					errorAt(spec.DefRow(y), x+1, "more than one hero found")
+				}
+			}
+			if t.Name == EntryTileType {
+				td, _ := spec.TileAt(x, y)
+				name := td.StringParam("name")
+				if len(name) > 0 && entries[name] {
+					errorAt(spec.DefRow(y), x+1, "more than one entry named %s found", name)
+				}
+				entries[name] = true
+			}
+			if t.JumpsLevel {
+				numJumpers++
+				if numJumpers > len(spec.NextLevelNames) {
+					errorAt(spec.DefRow(y), x+1, "there are %d next levels but more level pipes found",
+						len(spec.NextLevelNames))
+				}
+			}
+		}
+	}
+	if numHeroes == 0 {
+		errorAt(spec.DefLine, 0, "no hero found")
+	}
+	if numJumpers < len(spec.NextLevelNames) {
+		errorAt(spec.DefLine, 0, "there are %d next levels but %d level pipes", len(spec.NextLevelNames), numJumpers)
+	}
+
+	return errs.ErrOrNil()
+}
+
+// DefRow returns the row in level file of the yth line of level definition
+func (spec *LevelSpec) DefRow(y int) int {
+	if spec.DefLine == 0 {
+		// not from a file, use the 1-based index
+		return y + 1
+	}
+	return spec.DefLine + y
+}
+
+// isTiledTileset tells if a file is a tileset used by Tiled maps, which may sit next to them
+func isTiledTileset(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	return ext == ".tsx" || ext == ".tsj"
 }
 
 func parseRGB(str string) (sdl.Color, error) {
@@ -400,17 +410,149 @@ func parseLevelArr(str string) ([][]byte, error) {
 	var result [][]byte
 	lines := strings.Split(trimmed, "\n")
 
-	if len(lines) == 0 {
-		return nil, errors.New("failed to parse level arr: input is empty")
+	if len(trimmed) == 0 {
+		return nil, &ParseError{Msg: "level arr is empty"}
 	}
 
 	width := len(lines[0])
 
-	for _, l := range lines {
+	var errs ParseErrors
+	for i, l := range lines {
 		if len(l) != width {
-			return nil, errors.Errorf("failed to parse level arr: first line length is %d, but %d found", width, len(l))
+			errs = append(errs, &ParseError{Row: i + 1, Col: len(l) + 1,
+				Msg: fmt.Sprintf("first line length is %d, but %d found", width, len(l))})
 		}
 		result = append(result, []byte(l))
 	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 	return result, nil
 }
+
+func decWidth(decArr [][]byte) int {
+	if len(decArr) == 0 {
+		return 0
+	}
+	return len(decArr[0])
+}
+
+// specReader reads values from a level file, recording problems with positions instead of panic
+type specReader struct {
+	file string
+	conf *toml.Tree
+	errs ParseErrors
+}
+
+func (r *specReader) errorf(key string, format string, args ...interface{}) {
+	pos := r.conf.GetPosition(key)
+	r.errs = append(r.errs, &ParseError{
+		File: r.file,
+		Row:  pos.Line,
+		Col:  pos.Col,
+		Msg:  fmt.Sprintf("%s: ", key) + fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *specReader) get(key string) (interface{}, bool) {
+	if !r.conf.Has(key) {
+		r.errs = append(r.errs, &ParseError{File: r.file, Msg: fmt.Sprintf("missing key %s", key)})
+		return nil, false
+	}
+	return r.conf.Get(key), true
+}
+
+func (r *specReader) getString(key string) string {
+	v, ok := r.get(key)
+	if !ok {
+		return ""
+	}
+	str, ok := v.(string)
+	if !ok {
+		r.errorf(key, "should be a string")
+	}
+	return str
+}
+
+// getOptionalString returns empty if the key is not set
+func (r *specReader) getOptionalString(key string) string {
+	if !r.conf.Has(key) {
+		return ""
+	}
+	return r.getString(key)
+}
+
+// getOptionalInt returns 0 if the key is not set
+func (r *specReader) getOptionalInt(key string) int {
+	if !r.conf.Has(key) {
+		return 0
+	}
+	i, ok := r.conf.Get(key).(int64)
+	if !ok {
+		r.errorf(key, "should be an integer")
+	}
+	return int(i)
+}
+
+// getOptionalBool returns false if the key is not set
+func (r *specReader) getOptionalBool(key string) bool {
+	if !r.conf.Has(key) {
+		return false
+	}
+	b, ok := r.conf.Get(key).(bool)
+	if !ok {
+		r.errorf(key, "should be a bool")
+	}
+	return b
+}
+
+func (r *specReader) getStringArray(key string) []string {
+	v, ok := r.get(key)
+	if !ok {
+		return nil
+	}
+	arr, ok := v.([]interface{})
+	if !ok {
+		r.errorf(key, "should be an array")
+		return nil
+	}
+	var result []string
+	for _, item := range arr {
+		str, ok := item.(string)
+		if !ok {
+			r.errorf(key, "should only contain strings")
+			return nil
+		}
+		result = append(result, str)
+	}
+	return result
+}
+
+// getLevelArr returns the level arr and the line in file of its first row
+func (r *specReader) getLevelArr(key string) ([][]byte, int) {
+	str := r.getString(key)
+	if len(str) == 0 {
+		return nil, 0
+	}
+
+	// content of multi-line string starts from the line after the key, leading empty lines are trimmed
+	firstLine := r.conf.GetPosition(key).Line + 1 + len(str) - len(strings.TrimLeft(str, "\n"))
+
+	arr, err := parseLevelArr(str)
+	if err != nil {
+		var errs ParseErrors
+		errs.Add(r.file, err)
+		for _, e := range errs {
+			e.File = r.file
+			if e.Row > 0 {
+				e.Row += firstLine - 1
+			} else {
+				e.Row = firstLine
+			}
+			e.Msg = fmt.Sprintf("%s: %s", key, e.Msg)
+		}
+		r.errs = append(r.errs, errs...)
+		return nil, 0
+	}
+	return arr, firstLine
+}
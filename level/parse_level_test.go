@@ -0,0 +1,220 @@
+package level_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/zenja/mario/level"
+)
+
+func TestParseLevelSpecMissingKey(t *testing.T) {
+	filename := writeTempLevel(t, `[basic]
+name = "broken"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-color-rgb = "204, 237, 255"
+
+[level]
+def = """
+H...
+BBBB
+"""
+
+dec-def = """
+....
+....
+"""
+`)
+	defer os.Remove(filename)
+
+	_, err := level.ParseLevelSpec(filename)
+	errs, ok := err.(level.ParseErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error but was %v", err)
+	}
+	if errs[0].File != filename || errs[0].Msg != "missing key graphic.bg-file" {
+		t.Errorf("unexpected error: %s", errs[0])
+	}
+}
+
+func TestParseLevelSpecRowPosition(t *testing.T) {
+	filename := writeTempLevel(t, `[basic]
+name = "broken"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "204, 237, 255"
+
+[level]
+def = """
+H...
+....
+BBB
+"""
+
+dec-def = """
+....
+....
+....
+"""
+`)
+	defer os.Remove(filename)
+
+	_, err := level.ParseLevelSpec(filename)
+	errs, ok := err.(level.ParseErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error but was %v", err)
+	}
+	if errs[0].Row != 15 || errs[0].Col != 4 {
+		t.Errorf("expected short row at 15:4 but was %d:%d", errs[0].Row, errs[0].Col)
+	}
+}
+
+func TestParseLevelSpecLegend(t *testing.T) {
+	filename := writeTempLevel(t, `[basic]
+name = "legend"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "204, 237, 255"
+
+[legend]
+"@" = "hero"
+"B" = "brick-red"
+"$" = { type = "coin-box", coins = 5 }
+
+[level]
+def = """
+.@..
+.$C.
+BBBB
+"""
+
+dec-def = """
+....
+....
+....
+"""
+`)
+	defer os.Remove(filename)
+
+	spec, err := level.ParseLevelSpec(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiles := []struct {
+		x, y     int
+		tileType string
+		coins    int
+	}{
+		{1, 0, "hero", 0},
+		{1, 1, "coin-box", 5},
+		{2, 1, "coin-box", 3}, // default
+		{0, 2, "brick-red", 0},
+	}
+	for _, tile := range tiles {
+		td, ok := spec.TileAt(tile.x, tile.y)
+		if !ok || td.Type != tile.tileType {
+			t.Errorf("expected %s at (%d, %d) but was %v", tile.tileType, tile.x, tile.y, td)
+			continue
+		}
+		if tile.coins > 0 && td.IntParam("coins") != tile.coins {
+			t.Errorf("expected %d coins at (%d, %d) but was %d", tile.coins, tile.x, tile.y, td.IntParam("coins"))
+		}
+	}
+
+	if _, err := level.BuildLevel(spec, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseLevelSpecBadLegend(t *testing.T) {
+	filename := writeTempLevel(t, `[basic]
+name = "legend"
+
+[transfer]
+next-levels = []
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "204, 237, 255"
+
+[legend]
+"@" = "superhero"
+"$" = { type = "coin-box", coins = "many" }
+
+[level]
+def = """
+.H..
+BBBB
+"""
+
+dec-def = """
+....
+....
+"""
+`)
+	defer os.Remove(filename)
+
+	_, err := level.ParseLevelSpec(filename)
+	errs, ok := err.(level.ParseErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected 2 errors but was %v", err)
+	}
+}
+
+func TestValidateReportsHeroPositions(t *testing.T) {
+	spec := newTestSpec(
+		"H...",
+		"..H.",
+		"BBBB",
+	)
+	err := spec.Validate()
+	errs, ok := err.(level.ParseErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error but was %v", err)
+	}
+	if errs[0].Row != 2 || errs[0].Col != 3 {
+		t.Errorf("expected duplicated hero at 2:3 but was %d:%d", errs[0].Row, errs[0].Col)
+	}
+}
+
+func TestBuildLevelWithGroundAtEdges(t *testing.T) {
+	// grass ground at the first and last column used to index out of range
+	spec := newTestSpec(
+		".H..",
+		"....",
+		"LGGR",
+		"lggr",
+	)
+	if _, err := level.BuildLevel(spec, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func writeTempLevel(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
@@ -0,0 +1,135 @@
+package level
+
+import (
+	"log"
+
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/vector"
+)
+
+// REWIND_MS is how long of play before now can be rewound
+const REWIND_MS = 5000
+
+// rewindRecord is the level after a step, kept to rewind to
+// Removed tiles are only counted, since the level itself keeps them in the order they are removed
+type rewindRecord struct {
+	state           Snapshot
+	numRemovedTiles int
+}
+
+// rewindBuffer keeps records of the last steps of a level, the oldest one is overwritten when it is full
+// Records are reused as the ring goes round, so that recording a step doesn't allocate once the ring is warm
+type rewindBuffer struct {
+	// a ring of records, the latest one is at last
+	records []rewindRecord
+	last    int
+	size    int
+}
+
+// newRewindBuffer makes a buffer to rewind the given number of steps
+func newRewindBuffer(steps int) *rewindBuffer {
+	// the latest record is the level as it is now, which is one more than steps to go back
+	return &rewindBuffer{records: make([]rewindRecord, steps+1)}
+}
+
+// record keeps the level as it is now as the latest record
+func (rb *rewindBuffer) record(l *Level) {
+	rb.last = (rb.last + 1) % len(rb.records)
+	rec := &rb.records[rb.last]
+	l.saveSnapshot(&rec.state)
+	rec.numRemovedTiles = len(l.removedTiles)
+	if rb.size < len(rb.records) {
+		rb.size++
+	}
+}
+
+// latest returns the latest record, nil if there is none
+func (rb *rewindBuffer) latest() *rewindRecord {
+	if rb.size == 0 {
+		return nil
+	}
+	return &rb.records[rb.last]
+}
+
+// drop throws away the latest record
+func (rb *rewindBuffer) drop() {
+	if rb.size == 0 {
+		return
+	}
+	rb.last = (rb.last - 1 + len(rb.records)) % len(rb.records)
+	rb.size--
+}
+
+// clear throws away all records, their memory is kept to be reused
+func (rb *rewindBuffer) clear() {
+	rb.size = 0
+}
+
+// EnableRewind makes the level record its last REWIND_MS of play, which is rewound by holding rewind
+// Recording costs some work every step, so it is off unless asked for, e.g. when debugging
+func (l *Level) EnableRewind() {
+	if l.rewinds == nil {
+		l.rewinds = newRewindBuffer(REWIND_MS / SIMULATION_STEP_MS)
+	}
+}
+
+// RewindLeftMs returns how long of play can still be rewound
+func (l *Level) RewindLeftMs() int {
+	if l.rewinds == nil || l.rewinds.size == 0 {
+		return 0
+	}
+	// the latest record is the level as it is now
+	return (l.rewinds.size - 1) * SIMULATION_STEP_MS
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// rewind takes the level back by a step, the latest record kept is the level after last step
+// Level stays at the oldest record if there is nothing older, its time doesn't go on either
+func (l *Level) rewind() {
+	if l.rewinds.size > 1 {
+		l.rewinds.drop()
+	}
+	rec := l.rewinds.latest()
+	if rec == nil {
+		return
+	}
+
+	wasDead := l.TheHero.IsDead()
+	l.putBackTilesRemovedAfter(rec.numRemovedTiles)
+	if err := l.loadSnapshot(&rec.state); err != nil {
+		log.Fatalf("bug! failed to rewind level: %v", err)
+	}
+	// music is stopped when hero dies
+	if wasDead && !l.TheHero.IsDead() {
+		audio.PlayMusic()
+	}
+}
+
+// putBackRemovedTiles puts back tiles removed after the given ones were removed, which takes tiles back to a
+// snapshot much faster than building them again
+// It returns false if the given tiles are not the first ones removed, e.g. tiles have been reset since
+func (l *Level) putBackRemovedTiles(removedBefore []vector.TileID) bool {
+	if len(removedBefore) > len(l.removedTiles) {
+		return false
+	}
+	for i, tid := range removedBefore {
+		if l.removedTiles[i].tid != tid {
+			return false
+		}
+	}
+	l.putBackTilesRemovedAfter(len(removedBefore))
+	return true
+}
+
+// putBackTilesRemovedAfter puts back tiles removed after the first n ones, latest first
+func (l *Level) putBackTilesRemovedAfter(n int) {
+	for i := len(l.removedTiles) - 1; i >= n; i-- {
+		rt := l.removedTiles[i]
+		l.TileObjects[rt.tid.X][rt.tid.Y] = rt.obj
+		l.ObstMngr.setTileObst(rt.tid, rt.obst)
+	}
+	l.removedTiles = l.removedTiles[:n]
+}
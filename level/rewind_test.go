@@ -0,0 +1,100 @@
+package level_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/level"
+)
+
+func TestRewindPutsBackBrokenTiles(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"......",
+		"......",
+		".B....",
+		"......",
+		".H....",
+		"BBBBBB",
+	), clk)
+	l.EnableRewind()
+	l.Init()
+
+	tracker := event.NewTracker()
+	play := func(script []inputSpan) int {
+		inputs := expandScript(script)
+		for _, events := range inputs {
+			l.Update(tracker.Next(events))
+			clk.Advance(level.SIMULATION_STEP_MS)
+		}
+		return len(inputs)
+	}
+
+	play([]inputSpan{{steps: 20}})
+	before := l.Snapshot()
+	steps := play([]inputSpan{
+		{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_SPACE}},
+		{steps: 40, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+	})
+	if len(l.Snapshot().RemovedTiles) != 1 {
+		t.Fatal("expected hero to break the brick")
+	}
+
+	// going back as many steps as played gives the level as it was
+	play([]inputSpan{{steps: steps, keys: []event.Event{event.EVENT_KEYDOWN_REWIND}}})
+	if after := l.Snapshot(); !reflect.DeepEqual(before, after) {
+		t.Errorf("expected the level before breaking the brick after rewinding but was\n%+v\nnot\n%+v", after, before)
+	}
+	if l.TileObjects[1][2] == nil {
+		t.Errorf("expected the brick put back after rewinding")
+	}
+
+	// the level stays after its first step, which is at 1, when there is nothing older
+	play([]inputSpan{{steps: 100, keys: []event.Event{event.EVENT_KEYDOWN_REWIND}}})
+	if left := l.RewindLeftMs(); left != 0 {
+		t.Errorf("expected nothing left to rewind but was %d ms", left)
+	}
+	if ticks := l.Snapshot().Ticks - level.SIMULATION_STEP_MS; ticks != 1 {
+		t.Errorf("expected the level rewound to its first step at 1 but was at %d", ticks)
+	}
+}
+
+func TestRewindIsLimited(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"H..",
+		"BBB",
+	), clk)
+	l.EnableRewind()
+	l.Init()
+
+	runFrames(l, clk, 2*level.REWIND_MS/level.SIMULATION_STEP_MS)
+	if left := l.RewindLeftMs(); left > level.REWIND_MS || left <= level.REWIND_MS-level.SIMULATION_STEP_MS {
+		t.Errorf("expected about %d ms to rewind but was %d ms", level.REWIND_MS, left)
+	}
+}
+
+func TestRewindIsOffByDefault(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"H..",
+		"BBB",
+	), clk)
+	l.Init()
+
+	runFrames(l, clk, 10)
+	ticks := l.Ticks()
+	tracker := event.NewTracker()
+	for _, events := range expandScript([]inputSpan{{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_REWIND}}}) {
+		l.Update(tracker.Next(events))
+		clk.Advance(level.SIMULATION_STEP_MS)
+	}
+	if left := l.RewindLeftMs(); left != 0 {
+		t.Errorf("expected nothing recorded to rewind but was %d ms", left)
+	}
+	if l.Ticks() != ticks+10*level.SIMULATION_STEP_MS {
+		t.Errorf("expected level to go on when rewind is held but it was at %d", l.Ticks())
+	}
+}
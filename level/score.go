@@ -0,0 +1,60 @@
+package level
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/vector"
+)
+
+// ScoreTable tells how many points hero gets for what
+type ScoreTable struct {
+	// points for stomping enemies one after another without touching the ground,
+	// the last one is given for every further stomp
+	StompCombo []int
+
+	// killing an enemy with a fireball
+	Fireball int
+
+	// killing an enemy with a bumping shell
+	Shell int
+
+	// breaking a brick
+	Brick int
+
+	// eating a mushroom or a flower
+	PowerUp int
+}
+
+var DefaultScoreTable = ScoreTable{
+	StompCombo: []int{100, 200, 400, 500, 800, 1000, 2000, 4000, 5000, 8000},
+	Fireball:   200,
+	Shell:      500,
+	Brick:      50,
+	PowerUp:    1000,
+}
+
+// AddScore adds points to score and shows them floating up from where they are earned
+func (l *Level) AddScore(points int, rect sdl.Rect) {
+	if points <= 0 {
+		return
+	}
+	l.Score += points
+	l.AddEffect(NewScorePopupEffect(points, vector.Pos{rect.X + rect.W/2, rect.Y}, l.Ticks()))
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// addStompScore gives points for an enemy stomped, more if hero keeps stomping before landing
+func (l *Level) addStompScore(h *Hero, rect sdl.Rect) {
+	combo := l.ScoreTable.StompCombo
+	if len(combo) == 0 {
+		return
+	}
+	i := h.stompCombo
+	if i >= len(combo) {
+		i = len(combo) - 1
+	}
+	h.stompCombo++
+	l.AddScore(combo[i], rect)
+}
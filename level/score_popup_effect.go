@@ -0,0 +1,73 @@
+package level
+
+import (
+	"fmt"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+const (
+	score_popup_duration_ms = 800
+
+	// how fast the points float up, pixels per second
+	score_popup_velocity = 80
+)
+
+var _ Effect = &scorePopupEffect{}
+
+// scorePopupEffect shows points earned (or any short text) floating up for a while
+type scorePopupEffect struct {
+	text       string
+	pos        vector.Pos // level position of the top middle of text
+	startTicks uint32
+	lastTicks  uint32
+	subPixel   vector.Vec2D
+	finished   bool
+}
+
+func NewScorePopupEffect(points int, pos vector.Pos, ticks uint32) *scorePopupEffect {
+	return NewTextPopupEffect(fmt.Sprint(points), pos, ticks)
+}
+
+func NewTextPopupEffect(text string, pos vector.Pos, ticks uint32) *scorePopupEffect {
+	return &scorePopupEffect{
+		text:       text,
+		pos:        pos,
+		startTicks: ticks,
+	}
+}
+
+func (spe *scorePopupEffect) Update(ticks uint32) {
+	if ticks-spe.startTicks >= score_popup_duration_ms {
+		spe.finished = true
+		return
+	}
+	if spe.lastTicks == 0 {
+		spe.lastTicks = ticks
+		return
+	}
+
+	step := CalcVelocityStep(vector.Vec2D{0, -score_popup_velocity}, ticks, spe.lastTicks, nil, &spe.subPixel)
+	spe.pos.Y += step.Y
+
+	spe.lastTicks = ticks
+}
+
+func (spe *scorePopupEffect) Draw(camPos vector.Pos, ticks uint32) {
+	if spe.finished {
+		return
+	}
+	// roughly center the text, font glyphs are about 10 pixels wide
+	screenPos := vector.Pos{spe.pos.X - camPos.X - int32(len(spe.text))*5, spe.pos.Y - camPos.Y}
+	graphic.DrawText(spe.text, screenPos, sdl.Color{255, 255, 255, 0})
+}
+
+func (spe *scorePopupEffect) Finished() bool {
+	return spe.finished
+}
+
+func (spe *scorePopupEffect) OnFinished() {
+	// Do nothing
+}
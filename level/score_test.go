@@ -0,0 +1,34 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+)
+
+func TestStompCombo(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		".H........",
+		"..........",
+		"..........",
+		"..........",
+		".1........",
+		".1........",
+		"BBBBBBBBBB",
+	), clk)
+	l.Init()
+
+	// hero falls on two enemies at once, the second stomp is worth more
+	runFrames(l, clk, 100)
+
+	for _, e := range l.Enemies {
+		if !e.IsDead() {
+			t.Fatalf("expected both enemies to be stomped")
+		}
+	}
+	combo := l.ScoreTable.StompCombo
+	if want := combo[0] + combo[1]; l.Score != want {
+		t.Errorf("expected score to be %d but was %d", want, l.Score)
+	}
+}
@@ -14,14 +14,14 @@ type screenFadeEffect struct {
 	durationMs uint32
 	startTicks uint32
 	finished   bool
-	onFinished func()
+	onFinished hook
 }
 
 func NewScreenFadeEffect(fadeIn bool, durationMs uint32, ticks uint32) *screenFadeEffect {
-	return NewScreenFadeEffectEx(fadeIn, durationMs, ticks, nil)
+	return NewScreenFadeEffectEx(fadeIn, durationMs, ticks, hook{})
 }
 
-func NewScreenFadeEffectEx(fadeIn bool, durationMs uint32, ticks uint32, onFinished func()) *screenFadeEffect {
+func NewScreenFadeEffectEx(fadeIn bool, durationMs uint32, ticks uint32, onFinished hook) *screenFadeEffect {
 	return &screenFadeEffect{
 		res:        graphic.Res(graphic.RESOURCE_TYPE_BLACK_SCREEN),
 		fadeIn:     fadeIn,
@@ -54,7 +54,5 @@ func (sfe *screenFadeEffect) Finished() bool {
 }
 
 func (sfe *screenFadeEffect) OnFinished() {
-	if sfe.onFinished != nil {
-		sfe.onFinished()
-	}
+	sfe.onFinished.run()
 }
@@ -0,0 +1,352 @@
+package level
+
+import (
+	"container/list"
+	"encoding/gob"
+	"io"
+	"log"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// SNAPSHOT_VERSION is bumped whenever Snapshot changes, snapshots of other versions cannot be restored
+const SNAPSHOT_VERSION = 1
+
+// Snapshot is the full state of a running level at a moment, which the level can be restored to
+// Only what changes while playing is kept, the rest is built again from the level spec when restoring
+type Snapshot struct {
+	Version   int
+	LevelName string
+
+	// level time when the snapshot is taken
+	Ticks uint32
+
+	Coins int
+	Score int
+
+	TimeLeftMs     int
+	TimerLastTicks uint32
+	StartTicks     uint32
+	ClearTicks     uint32
+	NextLevelName  string
+	Completed      bool
+	GameOver       bool
+
+	Checkpoint     *vector.TileID
+	CollectedCoins []vector.TileID
+	LastEntityID   EntityID
+
+	// tiles removed, e.g. broken bricks, and tile objects which have changed, e.g. emptied myth boxes
+	RemovedTiles []vector.TileID
+	Tiles        []ObjectState
+
+	Enemies      []ObjectState
+	VolatileObjs []ObjectState
+	Effects      []EffectState
+	Hero         HeroState
+}
+
+// ObjectState is the state of an object in a snapshot
+// It is one flat struct for all kinds of objects, each kind uses the fields it needs
+type ObjectState struct {
+	Kind string
+	ID   EntityID
+
+	// the tile the object is built on, and the level to go for level pipes
+	TID  vector.TileID
+	Name string
+
+	Rect       sdl.Rect
+	Res        graphic.ResourceID
+	Velocity   vector.Vec2D
+	SubPixel   vector.Vec2D
+	StartTicks uint32
+	LastTicks  uint32
+	Dead       bool
+
+	// state of some kinds only
+	FacingRight      bool     // tortoise
+	GoingUp          bool     // eater flower
+	Active           bool     // touched checkpoint or goal, bounding myth box
+	Empty            bool     // myth box
+	CoinsLeft        int      // coin myth box
+	InsideStartTicks uint32   // tortoise
+	BumpStartTicks   uint32   // tortoise
+	FlagRect         sdl.Rect // goal
+}
+
+// EffectState is the state of an effect in a snapshot, like ObjectState
+type EffectState struct {
+	Kind string
+
+	Res        []graphic.ResourceID
+	Rects      []sdl.Rect
+	Velocities []vector.Vec2D
+	SubPixel   vector.Vec2D
+	Pos        vector.Pos
+	Text       string
+	Numbers    []int
+	StartTicks uint32
+	LastTicks  uint32
+	DurationMs uint32
+	Flag       bool
+	Finished   bool
+
+	// what the level does when the effect finishes
+	OnFinished hook
+}
+
+// HeroState is the state of the hero in a snapshot
+type HeroState struct {
+	Grade        int
+	GradeWhenDie int
+	Lives        int
+
+	Rect     sdl.Rect
+	Velocity vector.Vec2D
+	SubPixel vector.Vec2D
+
+	LastTicks      uint32
+	LastFireTicks  uint32
+	HurtStartTicks uint32
+
+	JumpBuffer  int
+	StompCombo  int
+	OnGround    bool
+	FacingRight bool
+	Dead        bool
+	Disabled    bool
+
+	UpPressed   bool
+	FPressed    bool
+	DownPressed bool
+}
+
+// Snapshot takes the full state of the level as it is now
+func (l *Level) Snapshot() *Snapshot {
+	s := &Snapshot{}
+	l.saveSnapshot(s)
+	for _, rt := range l.removedTiles {
+		s.RemovedTiles = append(s.RemovedTiles, rt.tid)
+	}
+	return s
+}
+
+// RestoreSnapshot puts the level back to the state of a snapshot taken from a level of the same spec
+// Hero stays the same object, only its state is restored
+func (l *Level) RestoreSnapshot(s *Snapshot) error {
+	if s.Version != SNAPSHOT_VERSION {
+		return errors.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	if s.LevelName != l.Spec.Name {
+		return errors.Errorf("snapshot of level %s cannot be restored to level %s", s.LevelName, l.Spec.Name)
+	}
+	if s.Hero.Grade < 0 || s.Hero.Grade > 2 {
+		return errors.Errorf("hero's grade should be 0, 1 or 2 but was %d", s.Hero.Grade)
+	}
+
+	// tiles removed since the snapshot are put back, e.g. when rewinding
+	// otherwise tiles are built again as they are in the spec, and then removed as they were
+	if !l.putBackRemovedTiles(s.RemovedTiles) {
+		newLevel, err := BuildLevel(l.Spec, l.clock)
+		if err != nil {
+			return errors.Wrap(err, "failed to build level")
+		}
+		l.TileObjects = newLevel.TileObjects
+		l.ObstMngr = newLevel.ObstMngr
+		l.removedTiles = nil
+		for _, tid := range s.RemovedTiles {
+			if !l.isLegalTile(tid) {
+				return errors.Errorf("removed tile (%d, %d) is out of level", tid.X, tid.Y)
+			}
+			l.RemoveObstacleTileObject(tid)
+		}
+	}
+	if err := l.loadSnapshot(s); err != nil {
+		return err
+	}
+
+	// records to rewind to don't know the tiles removed before the snapshot, and are not the past of it anyway
+	if l.rewinds != nil {
+		l.rewinds.clear()
+	}
+	return nil
+}
+
+// Encode writes the snapshot in binary, which is read by DecodeSnapshot
+func (s *Snapshot) Encode(w io.Writer) error {
+	if err := gob.NewEncoder(w).Encode(s); err != nil {
+		return errors.Wrap(err, "failed to encode snapshot")
+	}
+	return nil
+}
+
+func DecodeSnapshot(r io.Reader) (*Snapshot, error) {
+	s := &Snapshot{}
+	if err := gob.NewDecoder(r).Decode(s); err != nil {
+		return nil, errors.Wrap(err, "malformed snapshot")
+	}
+	if s.Version != SNAPSHOT_VERSION {
+		return nil, errors.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return s, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// saveSnapshot takes the state of the level into s, except removed tiles which are left to the caller
+// Slices of s are reused, so that taking the level again and again into the same s doesn't allocate much
+func (l *Level) saveSnapshot(s *Snapshot) {
+	*s = Snapshot{
+		Version:        SNAPSHOT_VERSION,
+		LevelName:      l.Spec.Name,
+		Ticks:          l.Ticks(),
+		Coins:          l.Coins,
+		Score:          l.Score,
+		TimeLeftMs:     l.timeLeftMs,
+		TimerLastTicks: l.timerLastTicks,
+		StartTicks:     l.startTicks,
+		ClearTicks:     l.clearTicks,
+		NextLevelName:  l.nextLevelName,
+		Completed:      l.completed,
+		GameOver:       l.gameOver,
+		LastEntityID:   l.lastEntityID,
+		Hero:           l.TheHero.saveState(),
+		CollectedCoins: s.CollectedCoins[:0],
+		Tiles:          s.Tiles[:0],
+		Enemies:        s.Enemies[:0],
+		VolatileObjs:   s.VolatileObjs[:0],
+		Effects:        s.Effects[:0],
+	}
+	if l.checkpoint != nil {
+		tid := *l.checkpoint
+		s.Checkpoint = &tid
+	}
+	for tid := range l.collectedCoins {
+		s.CollectedCoins = append(s.CollectedCoins, tid)
+	}
+	// the same level state always gives the same snapshot
+	sort.Slice(s.CollectedCoins, func(i, j int) bool {
+		a, b := s.CollectedCoins[i], s.CollectedCoins[j]
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+
+	for i := 0; i < int(l.NumTiles.X); i++ {
+		for j := 0; j < int(l.NumTiles.Y); j++ {
+			if mb, ok := l.TileObjects[i][j].(*mythBox); ok {
+				s.Tiles = append(s.Tiles, mb.saveState())
+			}
+		}
+	}
+	for _, e := range l.Enemies {
+		s.Enemies = append(s.Enemies, saveObjectState(e))
+	}
+	for e := l.VolatileObjs.Front(); e != nil; e = e.Next() {
+		s.VolatileObjs = append(s.VolatileObjs, saveObjectState(e.Value.(Object)))
+	}
+	for e := l.effects.Front(); e != nil; e = e.Next() {
+		s.Effects = append(s.Effects, saveEffectState(e.Value.(Effect)))
+	}
+}
+
+// loadSnapshot puts the level back to the state of s, except removed tiles which have been put back by the caller
+func (l *Level) loadSnapshot(s *Snapshot) error {
+	for _, ts := range s.Tiles {
+		if !l.isLegalTile(ts.TID) {
+			return errors.Errorf("tile (%d, %d) is out of level", ts.TID.X, ts.TID.Y)
+		}
+		mb, ok := l.TileObjects[ts.TID.X][ts.TID.Y].(*mythBox)
+		if !ok {
+			return errors.Errorf("tile (%d, %d) is not a myth box", ts.TID.X, ts.TID.Y)
+		}
+		if err := mb.loadState(ts); err != nil {
+			return err
+		}
+	}
+
+	var enemies []Enemy
+	for _, state := range s.Enemies {
+		e, err := loadEnemy(state)
+		if err != nil {
+			return err
+		}
+		enemies = append(enemies, e)
+	}
+	volatileObjs := list.New()
+	for _, state := range s.VolatileObjs {
+		vo, err := loadVolatileObject(state)
+		if err != nil {
+			return err
+		}
+		volatileObjs.PushBack(vo)
+	}
+	effects := list.New()
+	for _, es := range s.Effects {
+		eff, err := l.loadEffect(es)
+		if err != nil {
+			return err
+		}
+		effects.PushBack(eff)
+	}
+	l.Enemies = enemies
+	l.VolatileObjs = volatileObjs
+	l.effects = effects
+
+	l.TheHero.loadState(s.Hero)
+
+	l.Coins = s.Coins
+	l.Score = s.Score
+	l.timeLeftMs = s.TimeLeftMs
+	l.timerLastTicks = s.TimerLastTicks
+	l.startTicks = s.StartTicks
+	l.clearTicks = s.ClearTicks
+	l.nextLevelName = s.NextLevelName
+	l.completed = s.Completed
+	l.gameOver = s.GameOver
+	l.lastEntityID = s.LastEntityID
+	l.checkpoint = nil
+	if s.Checkpoint != nil {
+		tid := *s.Checkpoint
+		l.checkpoint = &tid
+	}
+	l.collectedCoins = make(map[vector.TileID]bool)
+	for _, tid := range s.CollectedCoins {
+		l.collectedCoins[tid] = true
+	}
+
+	// level time goes on from the snapshot
+	now := l.clock.Ticks()
+	if l.suspendedTicks != 0 {
+		now = l.suspendedTicks
+	}
+	l.ticksSuspended = now - s.Ticks
+	return nil
+}
+
+func (l *Level) isLegalTile(tid vector.TileID) bool {
+	return tid.X >= 0 && tid.Y >= 0 && tid.X < l.NumTiles.X && tid.Y < l.NumTiles.Y
+}
+
+// resID returns the ID of a resource an object shows, all of them are loaded by graphic package
+func resID(res graphic.Resource) graphic.ResourceID {
+	id, ok := graphic.ResID(res)
+	if !ok {
+		log.Fatal("bug! resource of an object is not loaded")
+	}
+	return id
+}
+
+// loadRes returns a resource of a snapshot, which may be broken and have an unknown ID
+func loadRes(id graphic.ResourceID) (graphic.Resource, error) {
+	res := graphic.Res(id)
+	if res == nil {
+		return nil, errors.Errorf("unknown resource %d", id)
+	}
+	return res, nil
+}
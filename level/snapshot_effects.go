@@ -0,0 +1,340 @@
+package level
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// kinds of effects in snapshots
+const (
+	effect_kind_break_tile     = "break-tile"
+	effect_kind_coin           = "coin"
+	effect_kind_dead_down      = "dead-down"
+	effect_kind_hero_into_pipe = "hero-into-pipe"
+	effect_kind_hero_out_pipe  = "hero-out-of-pipe"
+	effect_kind_hero_slide     = "hero-slide-down"
+	effect_kind_hero_walk_off  = "hero-walk-off"
+	effect_kind_level_complete = "level-complete"
+	effect_kind_score_popup    = "score-popup"
+	effect_kind_screen_fade    = "screen-fade"
+	effect_kind_shine          = "shine"
+	effect_kind_show_once      = "show-once"
+)
+
+// saveEffectState saves an effect, every kind of effects has to be known here
+func saveEffectState(eff Effect) EffectState {
+	switch e := eff.(type) {
+	case *breakTileEffect:
+		return EffectState{
+			Kind:       effect_kind_break_tile,
+			Res:        []graphic.ResourceID{resID(e.pieceRes)},
+			Rects:      []sdl.Rect{e.rectLT, e.rectRT, e.rectLB, e.rectRB},
+			Velocities: []vector.Vec2D{e.velLT, e.velRT, e.velLB, e.velRB},
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+		}
+
+	case *coinEffect:
+		return EffectState{
+			Kind:       effect_kind_coin,
+			Rects:      []sdl.Rect{e.tileRect, e.levelRect},
+			Velocities: []vector.Vec2D{e.velocity},
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+		}
+
+	case *deadDownEffect:
+		return EffectState{
+			Kind:       effect_kind_dead_down,
+			Res:        []graphic.ResourceID{resID(e.res)},
+			Rects:      []sdl.Rect{e.levelRect},
+			Velocities: []vector.Vec2D{e.velocity},
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *heroIntoPipeEffect:
+		return EffectState{
+			Kind:       effect_kind_hero_into_pipe,
+			Res:        []graphic.ResourceID{resID(e.res)},
+			Rects:      []sdl.Rect{e.levelRect},
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *heroOutOfPipeEffect:
+		return EffectState{
+			Kind:       effect_kind_hero_out_pipe,
+			Res:        []graphic.ResourceID{resID(e.res)},
+			Rects:      []sdl.Rect{e.finalRect, e.levelRect},
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *heroSlideDownEffect:
+		return EffectState{
+			Kind:       effect_kind_hero_slide,
+			Res:        []graphic.ResourceID{resID(e.res)},
+			Numbers:    []int{int(e.bottomY)},
+			SubPixel:   e.subPixel,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *heroWalkOffEffect:
+		var reses []graphic.ResourceID
+		for _, res := range e.reses {
+			reses = append(reses, resID(res))
+		}
+		return EffectState{
+			Kind:       effect_kind_hero_walk_off,
+			Res:        reses,
+			SubPixel:   e.subPixel,
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *levelCompleteEffect:
+		return EffectState{
+			Kind:       effect_kind_level_complete,
+			Numbers:    []int{e.flagBonus, e.timeBonus, e.scoreBefore},
+			StartTicks: e.startTicks,
+			Flag:       e.counted,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *scorePopupEffect:
+		return EffectState{
+			Kind:       effect_kind_score_popup,
+			Text:       e.text,
+			Pos:        e.pos,
+			SubPixel:   e.subPixel,
+			StartTicks: e.startTicks,
+			LastTicks:  e.lastTicks,
+			Finished:   e.finished,
+		}
+
+	case *screenFadeEffect:
+		return EffectState{
+			Kind:       effect_kind_screen_fade,
+			Flag:       e.fadeIn,
+			DurationMs: e.durationMs,
+			StartTicks: e.startTicks,
+			Finished:   e.finished,
+			OnFinished: e.onFinished,
+		}
+
+	case *shineEffect:
+		return EffectState{
+			Kind:       effect_kind_shine,
+			StartTicks: e.startTicks,
+			Finished:   e.finished,
+		}
+
+	case *showOnceEffect:
+		return EffectState{
+			Kind:       effect_kind_show_once,
+			Res:        []graphic.ResourceID{resID(e.res)},
+			Rects:      []sdl.Rect{e.levelRect},
+			StartTicks: e.startTicks,
+			DurationMs: e.durationMs,
+			Finished:   e.finished,
+		}
+	}
+
+	log.Fatalf("bug! effect %T cannot be saved in a snapshot", eff)
+	return EffectState{}
+}
+
+func (l *Level) loadEffect(s EffectState) (Effect, error) {
+	var reses []graphic.Resource
+	for _, id := range s.Res {
+		res, err := loadRes(id)
+		if err != nil {
+			return nil, err
+		}
+		reses = append(reses, res)
+	}
+	// how many of resources, rects, etc. each kind has
+	want := func(numReses, numRects, numVels, numNumbers int) error {
+		if len(reses) != numReses || len(s.Rects) != numRects || len(s.Velocities) != numVels || len(s.Numbers) != numNumbers {
+			return errors.Errorf("malformed %s effect in snapshot", s.Kind)
+		}
+		return nil
+	}
+	onFinished := s.OnFinished
+	if onFinished.Kind != hook_none {
+		onFinished.level = l
+	}
+
+	switch s.Kind {
+	case effect_kind_break_tile:
+		if err := want(1, 4, 4, 0); err != nil {
+			return nil, err
+		}
+		return &breakTileEffect{
+			pieceRes:   reses[0],
+			rectLT:     s.Rects[0],
+			rectRT:     s.Rects[1],
+			rectLB:     s.Rects[2],
+			rectRB:     s.Rects[3],
+			velLT:      s.Velocities[0],
+			velRT:      s.Velocities[1],
+			velLB:      s.Velocities[2],
+			velRB:      s.Velocities[3],
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			finished:   s.Finished,
+		}, nil
+
+	case effect_kind_coin:
+		if err := want(0, 2, 1, 0); err != nil {
+			return nil, err
+		}
+		return &coinEffect{
+			coinRes:    graphic.Res(graphic.RESOURCE_TYPE_COIN_0),
+			tileRect:   s.Rects[0],
+			levelRect:  s.Rects[1],
+			velocity:   s.Velocities[0],
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			finished:   s.Finished,
+		}, nil
+
+	case effect_kind_dead_down:
+		if err := want(1, 1, 1, 0); err != nil {
+			return nil, err
+		}
+		return &deadDownEffect{
+			res:        reses[0],
+			levelRect:  s.Rects[0],
+			velocity:   s.Velocities[0],
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			finished:   s.Finished,
+			onFinished: onFinished,
+		}, nil
+
+	case effect_kind_hero_into_pipe:
+		if err := want(1, 1, 0, 0); err != nil {
+			return nil, err
+		}
+		return &heroIntoPipeEffect{
+			res:        reses[0],
+			levelRect:  s.Rects[0],
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			finished:   s.Finished,
+			onFinished: onFinished,
+		}, nil
+
+	case effect_kind_hero_out_pipe:
+		if err := want(1, 2, 0, 0); err != nil {
+			return nil, err
+		}
+		return &heroOutOfPipeEffect{
+			res:        reses[0],
+			finalRect:  s.Rects[0],
+			levelRect:  s.Rects[1],
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			finished:   s.Finished,
+			onFinished: onFinished,
+		}, nil
+
+	case effect_kind_hero_slide:
+		if err := want(1, 0, 0, 1); err != nil {
+			return nil, err
+		}
+		return &heroSlideDownEffect{
+			h:          l.TheHero,
+			res:        reses[0],
+			bottomY:    int32(s.Numbers[0]),
+			lastTicks:  s.LastTicks,
+			subPixel:   s.SubPixel,
+			finished:   s.Finished,
+			onFinished: onFinished,
+		}, nil
+
+	case effect_kind_hero_walk_off:
+		if len(reses) == 0 {
+			return nil, errors.Errorf("malformed %s effect in snapshot", s.Kind)
+		}
+		return &heroWalkOffEffect{
+			h:          l.TheHero,
+			reses:      reses,
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			subPixel:   s.SubPixel,
+			finished:   s.Finished,
+			onFinished: onFinished,
+		}, nil
+
+	case effect_kind_level_complete:
+		if err := want(0, 0, 0, 3); err != nil {
+			return nil, err
+		}
+		return &levelCompleteEffect{
+			level:       l,
+			flagBonus:   s.Numbers[0],
+			timeBonus:   s.Numbers[1],
+			scoreBefore: s.Numbers[2],
+			startTicks:  s.StartTicks,
+			counted:     s.Flag,
+			finished:    s.Finished,
+			onFinished:  onFinished,
+		}, nil
+
+	case effect_kind_score_popup:
+		return &scorePopupEffect{
+			text:       s.Text,
+			pos:        s.Pos,
+			startTicks: s.StartTicks,
+			lastTicks:  s.LastTicks,
+			subPixel:   s.SubPixel,
+			finished:   s.Finished,
+		}, nil
+
+	case effect_kind_screen_fade:
+		sfe := NewScreenFadeEffectEx(s.Flag, s.DurationMs, s.StartTicks, onFinished)
+		sfe.finished = s.Finished
+		return sfe, nil
+
+	case effect_kind_shine:
+		se := NewShineEffect(l.TheHero, s.StartTicks)
+		// resources to show are picked on update, pick them now as it may be drawn before updated
+		se.Update(s.StartTicks)
+		se.finished = s.Finished
+		return se, nil
+
+	case effect_kind_show_once:
+		if err := want(1, 1, 0, 0); err != nil {
+			return nil, err
+		}
+		return &showOnceEffect{
+			res:        reses[0],
+			levelRect:  s.Rects[0],
+			startTicks: s.StartTicks,
+			durationMs: s.DurationMs,
+			finished:   s.Finished,
+		}, nil
+	}
+
+	return nil, errors.Errorf("unknown kind of effect in snapshot: %s", s.Kind)
+}
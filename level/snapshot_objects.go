@@ -0,0 +1,349 @@
+package level
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/zenja/mario/vector"
+)
+
+// kinds of objects in snapshots
+const (
+	object_kind_mushroom        = "mushroom"
+	object_kind_tortoise        = "tortoise"
+	object_kind_eater_flower    = "eater-flower"
+	object_kind_good_mushroom   = "good-mushroom"
+	object_kind_one_up_mushroom = "one-up-mushroom"
+	object_kind_upgrade_flower  = "upgrade-flower"
+	object_kind_level_jumper    = "level-jumper"
+	object_kind_coin            = "coin"
+	object_kind_checkpoint      = "checkpoint"
+	object_kind_goal            = "goal"
+	object_kind_fireball        = "fireball"
+	object_kind_myth_box        = "myth-box"
+)
+
+// saveObjectState saves an enemy or a volatile object, every kind of them has to be known here
+func saveObjectState(o Object) ObjectState {
+	switch o := o.(type) {
+	case *mushroomEnemy:
+		return ObjectState{
+			Kind:      object_kind_mushroom,
+			ID:        o.id,
+			Rect:      o.levelRect,
+			Res:       resID(o.currRes),
+			Velocity:  o.velocity,
+			SubPixel:  o.subPixel,
+			LastTicks: o.lastTicks,
+			Dead:      o.isDead,
+		}
+
+	case *tortoiseEnemy:
+		return ObjectState{
+			Kind:             object_kind_tortoise,
+			ID:               o.id,
+			Rect:             o.levelRect,
+			Res:              resID(o.currRes),
+			Velocity:         o.velocity,
+			SubPixel:         o.subPixel,
+			LastTicks:        o.lastTicks,
+			Dead:             o.isDead,
+			FacingRight:      o.isFacingRight,
+			InsideStartTicks: o.insideStartTicks,
+			BumpStartTicks:   o.bumpStartTicks,
+		}
+
+	case *eaterFlower:
+		return ObjectState{
+			Kind:      object_kind_eater_flower,
+			ID:        o.id,
+			TID:       o.tid,
+			Rect:      o.levelRect,
+			SubPixel:  o.subPixel,
+			LastTicks: o.lastTicks,
+			Dead:      o.isDead,
+			GoingUp:   o.goingUp,
+		}
+
+	case *goodMushroom:
+		kind := object_kind_good_mushroom
+		if o.oneUp {
+			kind = object_kind_one_up_mushroom
+		}
+		return ObjectState{
+			Kind:      kind,
+			ID:        o.id,
+			Rect:      o.levelRect,
+			Velocity:  o.velocity,
+			SubPixel:  o.subPixel,
+			LastTicks: o.lastTicks,
+			Dead:      o.isDead,
+		}
+
+	case *upgradeFlower:
+		return ObjectState{
+			Kind:      object_kind_upgrade_flower,
+			ID:        o.id,
+			Rect:      o.levelRect,
+			Velocity:  o.velocity,
+			SubPixel:  o.subPixel,
+			LastTicks: o.lastTicks,
+			Dead:      o.isDead,
+		}
+
+	case *levelJumper:
+		return ObjectState{
+			Kind: object_kind_level_jumper,
+			ID:   o.id,
+			Name: o.nextLevelName,
+			Rect: o.levelRect,
+			Dead: o.isDead,
+		}
+
+	case *coinEnemy:
+		return ObjectState{
+			Kind: object_kind_coin,
+			ID:   o.id,
+			TID:  o.tid,
+			Dead: o.isDead,
+		}
+
+	case *checkpoint:
+		return ObjectState{
+			Kind:   object_kind_checkpoint,
+			ID:     o.id,
+			TID:    o.tid,
+			Dead:   o.isDead,
+			Active: o.active,
+		}
+
+	case *goal:
+		return ObjectState{
+			Kind:      object_kind_goal,
+			ID:        o.id,
+			TID:       o.tid,
+			SubPixel:  o.subPixel,
+			LastTicks: o.lastTicks,
+			Dead:      o.isDead,
+			Active:    o.touched,
+			FlagRect:  o.flagRect,
+		}
+
+	case *fireball:
+		return ObjectState{
+			Kind:       object_kind_fireball,
+			ID:         o.id,
+			Rect:       o.levelRect,
+			Res:        resID(o.currRes),
+			Velocity:   o.velocity,
+			SubPixel:   o.subPixel,
+			StartTicks: o.startTicks,
+			LastTicks:  o.lastTicks,
+			Dead:       o.isDead,
+		}
+	}
+
+	log.Fatalf("bug! object %T cannot be saved in a snapshot", o)
+	return ObjectState{}
+}
+
+func loadEnemy(s ObjectState) (Enemy, error) {
+	pos := vector.Pos{s.Rect.X, s.Rect.Y}
+	var e Enemy
+	switch s.Kind {
+	case object_kind_mushroom:
+		m := NewMushroomEnemy(pos)
+		res, err := loadRes(s.Res)
+		if err != nil {
+			return nil, err
+		}
+		m.currRes = res
+		m.levelRect = s.Rect
+		m.velocity = s.Velocity
+		m.subPixel = s.SubPixel
+		m.lastTicks = s.LastTicks
+		e = m
+
+	case object_kind_tortoise:
+		t := NewTortoiseEnemy(pos).(*tortoiseEnemy)
+		res, err := loadRes(s.Res)
+		if err != nil {
+			return nil, err
+		}
+		t.currRes = res
+		t.levelRect = s.Rect
+		t.velocity = s.Velocity
+		t.subPixel = s.SubPixel
+		t.lastTicks = s.LastTicks
+		t.isFacingRight = s.FacingRight
+		t.insideStartTicks = s.InsideStartTicks
+		t.bumpStartTicks = s.BumpStartTicks
+		e = t
+
+	case object_kind_eater_flower:
+		ef := NewEaterFlower(s.TID)
+		ef.levelRect = s.Rect
+		ef.subPixel = s.SubPixel
+		ef.lastTicks = s.LastTicks
+		ef.goingUp = s.GoingUp
+		e = ef
+
+	case object_kind_good_mushroom, object_kind_one_up_mushroom:
+		var gm *goodMushroom
+		if s.Kind == object_kind_one_up_mushroom {
+			gm = NewOneUpMushroom(pos)
+		} else {
+			gm = NewGoodMushroom(pos)
+		}
+		gm.levelRect = s.Rect
+		gm.velocity = s.Velocity
+		gm.subPixel = s.SubPixel
+		gm.lastTicks = s.LastTicks
+		e = gm
+
+	case object_kind_upgrade_flower:
+		uf := NewUpgradeFlower(pos)
+		uf.levelRect = s.Rect
+		uf.velocity = s.Velocity
+		uf.subPixel = s.SubPixel
+		uf.lastTicks = s.LastTicks
+		e = uf
+
+	case object_kind_level_jumper:
+		e = &levelJumper{nextLevelName: s.Name, levelRect: s.Rect}
+
+	case object_kind_coin:
+		e = NewCoinEnemy(s.TID)
+
+	case object_kind_checkpoint:
+		cp := NewCheckpoint(s.TID)
+		cp.active = s.Active
+		e = cp
+
+	case object_kind_goal:
+		g := NewGoal(s.TID)
+		g.touched = s.Active
+		g.flagRect = s.FlagRect
+		g.subPixel = s.SubPixel
+		g.lastTicks = s.LastTicks
+		e = g
+
+	default:
+		return nil, errors.Errorf("unknown kind of enemy in snapshot: %s", s.Kind)
+	}
+
+	e.setID(s.ID)
+	if s.Dead {
+		e.Kill()
+	}
+	return e, nil
+}
+
+func loadVolatileObject(s ObjectState) (volatileObject, error) {
+	if s.Kind != object_kind_fireball {
+		return nil, errors.Errorf("unknown kind of volatile object in snapshot: %s", s.Kind)
+	}
+	f := NewFireball(sdl.Rect{}, true, false, s.StartTicks)
+	res, err := loadRes(s.Res)
+	if err != nil {
+		return nil, err
+	}
+	f.id = s.ID
+	f.currRes = res
+	f.levelRect = s.Rect
+	f.velocity = s.Velocity
+	f.subPixel = s.SubPixel
+	f.lastTicks = s.LastTicks
+	f.isDead = s.Dead
+	return f, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Myth box
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (mb *mythBox) saveState() ObjectState {
+	s := ObjectState{
+		Kind:      object_kind_myth_box,
+		TID:       GetTileID(vector.Pos{mb.tileRect.X, mb.tileRect.Y}, false, false),
+		Rect:      mb.levelRect,
+		Res:       resID(mb.currRes),
+		Velocity:  mb.velocity,
+		SubPixel:  mb.subPixel,
+		LastTicks: mb.lastTicks,
+		Active:    mb.isBounding,
+		Empty:     mb.isEmpty,
+	}
+	if ca, ok := mb.actor.(*coinActor); ok {
+		s.CoinsLeft = ca.numCoinsLeft
+	}
+	return s
+}
+
+// loadState restores a myth box built from the same tile, what it gives is decided by the tile
+func (mb *mythBox) loadState(s ObjectState) error {
+	res, err := loadRes(s.Res)
+	if err != nil {
+		return err
+	}
+	mb.currRes = res
+	mb.levelRect = s.Rect
+	mb.velocity = s.Velocity
+	mb.subPixel = s.SubPixel
+	mb.lastTicks = s.LastTicks
+	mb.isBounding = s.Active
+	mb.isEmpty = s.Empty
+	if ca, ok := mb.actor.(*coinActor); ok {
+		ca.numCoinsLeft = s.CoinsLeft
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Hero
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (h *Hero) saveState() HeroState {
+	return HeroState{
+		Grade:          h.grade,
+		GradeWhenDie:   h.gradeWhenDie,
+		Lives:          h.lives,
+		Rect:           h.levelRect,
+		Velocity:       h.velocity,
+		SubPixel:       h.subPixel,
+		LastTicks:      h.lastTicks,
+		LastFireTicks:  h.lastFireTicks,
+		HurtStartTicks: h.hurtStartTicks,
+		JumpBuffer:     h.jumpBuffer,
+		StompCombo:     h.stompCombo,
+		OnGround:       h.isOnGround,
+		FacingRight:    h.isFacingRight,
+		Dead:           h.isDead,
+		Disabled:       h.disabled,
+		UpPressed:      h.upPressed,
+		FPressed:       h.fPressed,
+		DownPressed:    h.downPressed,
+	}
+}
+
+func (h *Hero) loadState(s HeroState) {
+	h.RestoreState(s.Grade, s.Lives)
+	h.gradeWhenDie = s.GradeWhenDie
+	h.levelRect = s.Rect
+	h.velocity = s.Velocity
+	h.subPixel = s.SubPixel
+	h.lastTicks = s.LastTicks
+	h.lastFireTicks = s.LastFireTicks
+	h.hurtStartTicks = s.HurtStartTicks
+	h.jumpBuffer = s.JumpBuffer
+	h.stompCombo = s.StompCombo
+	h.isOnGround = s.OnGround
+	h.isFacingRight = s.FacingRight
+	h.isDead = s.Dead
+	h.disabled = s.Disabled
+	h.upPressed = s.UpPressed
+	h.fPressed = s.FPressed
+	h.downPressed = s.DownPressed
+	h.updateRes()
+}
@@ -0,0 +1,129 @@
+package level_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/event"
+	"github.com/zenja/mario/level"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestSnapshotRestoresLevel(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		"............",
+		"............",
+		".B.M........",
+		"............",
+		".H......2...",
+		"BBBBBBBBBBBB",
+	), clk)
+	l.Init()
+
+	// break the brick above, then jump into the myth box on the right
+	inputs := expandScript([]inputSpan{
+		{steps: 20},
+		{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_SPACE}},
+		{steps: 40},
+		{steps: 12, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+		{steps: 10, keys: []event.Event{event.EVENT_KEYDOWN_SPACE}},
+		{steps: 20},
+	})
+	tracker := event.NewTracker()
+	for _, events := range inputs {
+		l.Update(tracker.Next(events))
+		clk.Advance(level.SIMULATION_STEP_MS)
+	}
+
+	var buf bytes.Buffer
+	if err := l.Snapshot().Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	s, err := level.DecodeSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.RemovedTiles) != 1 || len(s.Tiles) != 1 || !s.Tiles[0].Active && !s.Tiles[0].Empty {
+		t.Fatalf("expected the brick broken and the myth box hit but removed tiles were %v and tiles %+v",
+			s.RemovedTiles, s.Tiles)
+	}
+
+	// the level goes on the same from the snapshot, however many times it is restored
+	run := func() *level.Snapshot {
+		inputs := expandScript([]inputSpan{
+			{steps: 30, keys: []event.Event{event.EVENT_KEYDOWN_RIGHT}},
+			{steps: 100},
+		})
+		for _, events := range inputs {
+			l.Update(tracker.Next(events))
+			clk.Advance(level.SIMULATION_STEP_MS)
+		}
+		return l.Snapshot()
+	}
+	first := run()
+	// level time goes on from the snapshot, not from the game time
+	clk.Advance(12345)
+	if err := l.RestoreSnapshot(s); err != nil {
+		t.Fatal(err)
+	}
+	if l.Ticks() != s.Ticks {
+		t.Errorf("expected level time to be %d after restoring but was %d", s.Ticks, l.Ticks())
+	}
+	second := run()
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same level after restoring a snapshot but was\n%+v\nand\n%+v", first, second)
+	}
+}
+
+func TestSnapshotKeepsPendingEffects(t *testing.T) {
+	spec := newTestSpec(
+		"H....F..",
+		"BBBBBBBB",
+	)
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, spec, clk)
+	l.Init()
+
+	// walk into the pole, the level is completed by effects one after another
+	var right intsets.Sparse
+	right.Insert(int(event.EVENT_KEYDOWN_RIGHT))
+	tracker := event.NewTracker()
+	for i := 0; i < 100; i++ {
+		l.Update(tracker.Next(&right))
+		clk.Advance(level.SIMULATION_STEP_MS)
+	}
+	if _, ok := l.ClearTime(); !ok {
+		t.Fatal("expected hero to reach the goal")
+	}
+
+	// another level of the same spec goes on from the snapshot
+	otherClk := clock.NewManualClock(99999)
+	other := mustBuildLevel(t, spec, otherClk)
+	if err := other.RestoreSnapshot(l.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	runFrames(other, otherClk, 1000)
+	if !other.Completed() {
+		t.Errorf("expected the level restored to be completed")
+	}
+	if other.Score <= 0 {
+		t.Errorf("expected bonuses added to score but was %d", other.Score)
+	}
+}
+
+func TestRestoreSnapshotOfOtherLevel(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	spec := newTestSpec(
+		"H..",
+		"BBB",
+	)
+	l := mustBuildLevel(t, spec, clk)
+	s := l.Snapshot()
+	s.LevelName = "other"
+	if err := l.RestoreSnapshot(s); err == nil {
+		t.Errorf("expected error restoring a snapshot of another level")
+	}
+}
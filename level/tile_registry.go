@@ -0,0 +1,315 @@
+package level
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// name of the tile type where hero starts, every level has exactly one
+const HeroTileType = "hero"
+
+// TileType is a kind of tile or entity which can be placed in level definition
+// To add a new kind, register it in an init() with RegisterTileType, usually in the file where it is implemented
+type TileType struct {
+	Name string
+
+	// default character in level definition, a level can use another one with its [legend]
+	Glyph byte
+
+	// obstacle type of the tile: not_obst, normal_obst, enemy_only_obst or up_thru_obst
+	Obst obstType
+
+	// params accepted by the type with default values, see TileDef
+	Params map[string]interface{}
+
+	// if true, each tile of this type takes a name from LevelSpec.NextLevelNames in order
+	JumpsLevel bool
+
+	// NewTile creates the tile object, nil if the type has no tile object (e.g. an enemy)
+	NewTile func(ctx *TileContext) Object
+
+	// NewEnemy creates the enemy spawned at the tile, nil if the type spawns nothing
+	NewEnemy func(ctx *TileContext) Enemy
+}
+
+// TileContext is what a tile type knows when building its tile
+type TileContext struct {
+	TID vector.TileID
+	Pos vector.Pos // left top of the tile in level
+	Def TileDef
+
+	// only for types which jump level
+	NextLevelName string
+
+	spec *LevelSpec
+}
+
+// NeighbourType returns the type name of the tile at the offset, empty if out of level or unknown
+func (ctx *TileContext) NeighbourType(dx, dy int) string {
+	if ctx.spec == nil {
+		// a tile on its own, see NewPreviewObject
+		return ""
+	}
+	t := ctx.spec.typeAt(int(ctx.TID.X)+dx, int(ctx.TID.Y)+dy)
+	if t == nil {
+		return ""
+	}
+	return t.Name
+}
+
+var (
+	tileTypesByName  = make(map[string]*TileType)
+	tileTypesByGlyph = make(map[byte]*TileType)
+)
+
+// RegisterTileType adds a tile type, names and default characters must be unique
+func RegisterTileType(t TileType) {
+	if _, ok := tileTypesByName[t.Name]; ok {
+		log.Fatalf("tile type %s already registered", t.Name)
+	}
+	if existing, ok := tileTypesByGlyph[t.Glyph]; ok {
+		log.Fatalf("tile type %s uses the same character '%c' as %s", t.Name, t.Glyph, existing.Name)
+	}
+	tileTypesByName[t.Name] = &t
+	tileTypesByGlyph[t.Glyph] = &t
+}
+
+// GetTileType returns a registered tile type by name
+func GetTileType(name string) (TileType, bool) {
+	t, ok := tileTypesByName[name]
+	if !ok {
+		return TileType{}, false
+	}
+	return *t, true
+}
+
+// TileTypeNames returns names of all tile types, sorted
+func TileTypeNames() []string {
+	var names []string
+	for name := range tileTypesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkParam checks if a param is accepted by the type and has the type of its default value
+func (t *TileType) checkParam(name string, value interface{}) error {
+	def, ok := t.Params[name]
+	if !ok {
+		return fmt.Errorf("unknown param %s of %s", name, t.Name)
+	}
+	if reflect.TypeOf(value) != reflect.TypeOf(def) {
+		return fmt.Errorf("param %s should be of type %T", name, def)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Built-in tile types
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func init() {
+	RegisterTileType(TileType{Name: "empty", Glyph: '.', Obst: not_obst})
+
+	// Invisible block
+	RegisterTileType(TileType{
+		Name:  "invisible-block",
+		Glyph: '#',
+		Obst:  normal_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewInvisibleTileObject(ctx.TID)
+		},
+	})
+
+	// Invisible block only to enemies
+	RegisterTileType(TileType{
+		Name:  "enemy-only-block",
+		Glyph: '"',
+		Obst:  enemy_only_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewInvisibleTileObject(ctx.TID)
+		},
+	})
+
+	// Bricks
+	registerBrick("brick-yellow", 'B', graphic.RESOURCE_TYPE_BRICK_YELLOW, graphic.RESOURCE_TYPE_BRICK_PIECE_YELLOW)
+	registerBrick("brick-red", 'D', graphic.RESOURCE_TYPE_BRICK_RED, graphic.RESOURCE_TYPE_BRICK_PIECE_RED)
+
+	// Ground with grass, hero can jump through it from below
+	registerGround("grass-ground-left", 'L', up_thru_obst, graphic.RESOURCE_TYPE_GRASS_GROUD_LEFT, -1)
+	registerSingleTile("grass-ground-mid", 'G', up_thru_obst, graphic.RESOURCE_TYPE_GRASS_GROUD_MID, ZINDEX_0)
+	registerGround("grass-ground-right", 'R', up_thru_obst, graphic.RESOURCE_TYPE_GRASS_GROUD_RIGHT, 1)
+
+	// Inner ground
+	registerGround("ground-left", 'l', not_obst, graphic.RESOURCE_TYPE_GROUD_LEFT, -1)
+	registerSingleTile("ground-mid", 'g', not_obst, graphic.RESOURCE_TYPE_GROUD_MID, ZINDEX_0)
+	registerGround("ground-right", 'r', not_obst, graphic.RESOURCE_TYPE_GROUD_RIGHT, 1)
+
+	// Myth box for coins
+	RegisterTileType(TileType{
+		Name:   "coin-box",
+		Glyph:  'C',
+		Obst:   normal_obst,
+		Params: map[string]interface{}{"coins": int64(3)},
+		NewTile: func(ctx *TileContext) Object {
+			return NewCoinMythBox(ctx.Pos, ctx.Def.IntParam("coins"))
+		},
+	})
+
+	// Myth box for mushrooms
+	RegisterTileType(TileType{
+		Name:  "mushroom-box",
+		Glyph: 'M',
+		Obst:  normal_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewMushroomMythBox(ctx.Pos)
+		},
+	})
+
+	// Myth box for a 1-up mushroom
+	RegisterTileType(TileType{
+		Name:  "1up-box",
+		Glyph: 'U',
+		Obst:  normal_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewOneUpMythBox(ctx.Pos)
+		},
+	})
+
+	// Pipes
+	registerSingleTile("pipe-left-mid", '[', normal_obst, graphic.RESOURCE_TYPE_PIPE_LEFT_MID, ZINDEX_4)
+	registerSingleTile("pipe-right-mid", ']', normal_obst, graphic.RESOURCE_TYPE_PIPE_RIGHT_MID, ZINDEX_4)
+	registerSingleTile("pipe-left-top", '(', normal_obst, graphic.RESOURCE_TYPE_PIPE_LEFT_TOP, ZINDEX_4)
+	registerSingleTile("pipe-right-top", ')', normal_obst, graphic.RESOURCE_TYPE_PIPE_RIGHT_TOP, ZINDEX_4)
+	registerSingleTile("pipe-left-bottom", '<', normal_obst, graphic.RESOURCE_TYPE_PIPE_LEFT_BOTTOM, ZINDEX_4)
+	registerSingleTile("pipe-right-bottom", '>', normal_obst, graphic.RESOURCE_TYPE_PIPE_RIGHT_BOTTOM, ZINDEX_4)
+
+	// left middle of pipe, with eater flower
+	RegisterTileType(TileType{
+		Name:  "pipe-left-mid-eater",
+		Glyph: 'E',
+		Obst:  normal_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewSingleTileObject(graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_MID), ctx.TID, ZINDEX_4)
+		},
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewEaterFlower(ctx.TID)
+		},
+	})
+
+	// left top of pipe that will jump level
+	RegisterTileType(TileType{
+		Name:       "level-pipe-left-top",
+		Glyph:      '{',
+		Obst:       normal_obst,
+		JumpsLevel: true,
+		NewTile: func(ctx *TileContext) Object {
+			return NewSingleTileObject(graphic.Res(graphic.RESOURCE_TYPE_PIPE_LEFT_TOP), ctx.TID, ZINDEX_4)
+		},
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewLevelJumper(ctx.TID, ctx.NextLevelName)
+		},
+	})
+
+	// right top of pipe that will jump level
+	registerSingleTile("level-pipe-right-top", '}', normal_obst, graphic.RESOURCE_TYPE_PIPE_RIGHT_TOP, ZINDEX_4)
+
+	// Water
+	RegisterTileType(TileType{
+		Name:  "water-surface",
+		Glyph: 'W',
+		Obst:  not_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewWaterSurfaceAnimationObject(ctx.TID)
+		},
+	})
+	registerSingleTile("water", 'w', not_obst, graphic.RESOURCE_TYPE_WATER_FULL, ZINDEX_1)
+
+	// Coin
+	RegisterTileType(TileType{
+		Name:  "coin",
+		Glyph: 'c',
+		Obst:  not_obst,
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewCoinEnemy(ctx.TID)
+		},
+	})
+
+	// Enemies
+	RegisterTileType(TileType{
+		Name:  "mushroom-enemy",
+		Glyph: '1',
+		Obst:  not_obst,
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewMushroomEnemy(ctx.Pos)
+		},
+	})
+	RegisterTileType(TileType{
+		Name:  "tortoise-enemy",
+		Glyph: '2',
+		Obst:  not_obst,
+		NewEnemy: func(ctx *TileContext) Enemy {
+			return NewTortoiseEnemy(ctx.Pos)
+		},
+	})
+
+	// Hero, created by BuildLevel itself
+	RegisterTileType(TileType{Name: HeroTileType, Glyph: 'H', Obst: not_obst})
+}
+
+func registerSingleTile(name string, glyph byte, obst obstType, resID graphic.ResourceID, zIndex int) {
+	RegisterTileType(TileType{
+		Name:  name,
+		Glyph: glyph,
+		Obst:  obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewSingleTileObject(graphic.Res(resID), ctx.TID, zIndex)
+		},
+	})
+}
+
+func registerBrick(name string, glyph byte, mainResID, pieceResID graphic.ResourceID) {
+	RegisterTileType(TileType{
+		Name:  name,
+		Glyph: glyph,
+		Obst:  normal_obst,
+		NewTile: func(ctx *TileContext) Object {
+			return NewBreakableTileObject(graphic.Res(mainResID), graphic.Res(pieceResID), ctx.Pos, ZINDEX_0)
+		},
+	})
+}
+
+// registerGround registers a ground edge, which draws ground behind it if there is more ground outside the edge
+// side is -1 for left edge and 1 for right edge
+func registerGround(name string, glyph byte, obst obstType, resID graphic.ResourceID, side int) {
+	RegisterTileType(TileType{
+		Name:  name,
+		Glyph: glyph,
+		Obst:  obst,
+		NewTile: func(ctx *TileContext) Object {
+			if needGroundBehind(ctx.NeighbourType(side, 0), side) {
+				return NewOverlapTilesObject(
+					[]graphic.ResourceID{graphic.RESOURCE_TYPE_GROUD_MID, resID}, ctx.TID, ZINDEX_1)
+			}
+			return NewSingleTileObject(graphic.Res(resID), ctx.TID, ZINDEX_1)
+		},
+	})
+}
+
+func needGroundBehind(neighbour string, side int) bool {
+	switch neighbour {
+	case "ground-mid":
+		return true
+	case "ground-left", "grass-ground-left":
+		return side < 0
+	case "ground-right", "grass-ground-right":
+		return side > 0
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/level"
+	"github.com/zenja/mario/vector"
+)
+
+func TestGetTileType(t *testing.T) {
+	tt, ok := level.GetTileType("brick-yellow")
+	if !ok {
+		t.Fatal("brick-yellow not registered")
+	}
+	if tt.Glyph != 'B' {
+		t.Errorf("expected brick-yellow to use 'B' but was '%c'", tt.Glyph)
+	}
+
+	found := false
+	for _, name := range level.TileTypeNames() {
+		if name == "brick-yellow" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("brick-yellow not listed in tile type names")
+	}
+}
+
+func TestBuildLevelWithRegisteredTileType(t *testing.T) {
+	var built []vector.TileID
+	level.RegisterTileType(level.TileType{
+		Name:  "test-coin-column",
+		Glyph: 'Z',
+		NewEnemy: func(ctx *level.TileContext) level.Enemy {
+			built = append(built, ctx.TID)
+			if ctx.NeighbourType(0, 1) != "brick-yellow" {
+				t.Errorf("expected brick below %v but was %q", ctx.TID, ctx.NeighbourType(0, 1))
+			}
+			return level.NewCoinEnemy(ctx.TID)
+		},
+	})
+
+	spec := newTestSpec(
+		".H.Z",
+		".Z.B",
+		".BBB",
+	)
+	l := mustBuildLevel(t, spec, nil)
+
+	if len(built) != 2 || built[0] != (vector.TileID{3, 0}) || built[1] != (vector.TileID{1, 1}) {
+		t.Errorf("expected tiles built at (3, 0) and (1, 1) but was %v", built)
+	}
+	if len(l.Enemies) != 2 {
+		t.Errorf("expected 2 enemies but was %d", len(l.Enemies))
+	}
+}
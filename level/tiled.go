@@ -0,0 +1,336 @@
+package level
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/zenja/mario/vector"
+)
+
+// ParseTiledLevelSpec parses a map made with the Tiled editor, either .tmx or .tmj
+// The returned error is ParseErrors like ParseLevelSpec, rows and columns of tiles are counted in tiles from 1
+//
+// Tiles of tilesets stand for the tile type named by their class, other properties of a tile are params of
+// the tile type like in a [legend] table; a tile with a "decoration" property (grass or tree) stands for
+// a decoration instead. Tile layers are drawn in order, so tiles of later layers replace earlier ones.
+//
+// Objects are placed at the tile of their left top by their class, which is either:
+//   - a tile type, like hero or mushroom-enemy; a level pipe takes the name of its next level from its
+//     "next-level" property
+//   - "pipe", a rectangle 2 tiles wide which becomes a pipe of its height; it jumps level if it has a
+//     "next-level" property and has an eater flower at its bottom if its "eater" property is true
+//
+// Map properties are name (the file name without extension if not set), bg-file, bg-color-rgb,
+// next-levels which is a comma separated list of next levels for level pipes without a "next-level",
+// in the order they appear from left top like in a TOML level file, goal-level, time-limit which is an int,
+// and persistent and secret which are bools.
+func ParseTiledLevelSpec(levelFile string) (*LevelSpec, error) {
+	m, err := loadTiledMap(levelFile)
+	if err != nil {
+		return nil, ParseErrors{&ParseError{File: levelFile, Msg: err.Error()}}
+	}
+
+	b := &tiledSpecBuilder{
+		file:         levelFile,
+		m:            m,
+		jumperNames:  make(map[vector.TileID]string),
+		legendGlyphs: make(map[string]byte),
+	}
+	spec := b.build()
+	if len(b.errs) > 0 {
+		return nil, b.errs
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// tiledSpecBuilder builds a level spec from a Tiled map, collecting all problems found
+type tiledSpecBuilder struct {
+	file string
+	m    *tiledMap
+	spec *LevelSpec
+
+	// next levels set by objects
+	jumperNames map[vector.TileID]string
+
+	// characters allocated in legend for tiles with params, by type and params
+	legendGlyphs map[string]byte
+
+	errs ParseErrors
+}
+
+func (b *tiledSpecBuilder) build() *LevelSpec {
+	m := b.m
+	if m.Width <= 0 || m.Height <= 0 || m.TileWidth <= 0 || m.TileHeight <= 0 {
+		b.errorf(0, 0, "bad map size %dx%d with tiles of %dx%d", m.Width, m.Height, m.TileWidth, m.TileHeight)
+		return nil
+	}
+
+	name := b.stringProperty("name", false)
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(b.file), filepath.Ext(b.file))
+	}
+	b.spec = &LevelSpec{
+		Name:       name,
+		BgFilename: b.stringProperty("bg-file", true),
+		LevelArr:   filledArr(m.Width, m.Height, '.'),
+		DecArr:     filledArr(m.Width, m.Height, '.'),
+		Legend:     make(map[byte]TileDef),
+		Filename:   b.file,
+	}
+	if rgb := b.stringProperty("bg-color-rgb", false); rgb != "" {
+		bgColor, err := parseRGB(rgb)
+		if err != nil {
+			b.errorf(0, 0, "map property bg-color-rgb: %s", err)
+		}
+		b.spec.BgColor = bgColor
+	}
+
+	for _, layer := range m.TileLayers {
+		for i, gid := range layer {
+			if gid == 0 {
+				continue
+			}
+			b.placeGID(i%m.Width, i/m.Width, gid)
+		}
+	}
+	for _, o := range m.Objects {
+		b.placeObject(o)
+	}
+
+	b.spec.NextLevelNames = b.nextLevelNames()
+	b.spec.GoalLevelName = b.stringProperty("goal-level", false)
+	if v, ok := m.Properties["time-limit"]; ok {
+		timeLimit, ok := v.(int64)
+		if !ok || timeLimit < 0 {
+			b.errorf(0, 0, "map property time-limit should be a non-negative int")
+		}
+		b.spec.TimeLimit = int(timeLimit)
+	}
+	if v, ok := m.Properties["persistent"]; ok {
+		persistent, ok := v.(bool)
+		if !ok {
+			b.errorf(0, 0, "map property persistent should be a bool")
+		}
+		b.spec.Persistent = persistent
+	}
+	if v, ok := m.Properties["secret"]; ok {
+		secret, ok := v.(bool)
+		if !ok {
+			b.errorf(0, 0, "map property secret should be a bool")
+		}
+		b.spec.Secret = secret
+	}
+	return b.spec
+}
+
+func (b *tiledSpecBuilder) errorf(row, col int, format string, args ...interface{}) {
+	b.errs = append(b.errs, &ParseError{File: b.file, Row: row, Col: col, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (b *tiledSpecBuilder) stringProperty(name string, required bool) string {
+	v, ok := b.m.Properties[name]
+	if !ok {
+		if required {
+			b.errorf(0, 0, "missing map property %s", name)
+		}
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		b.errorf(0, 0, "map property %s should be a string", name)
+	}
+	return s
+}
+
+// placeGID places a tile of a tile layer
+func (b *tiledSpecBuilder) placeGID(x, y int, gid uint32) {
+	tile := b.m.tileOf(gid)
+	if tile == nil {
+		b.errorf(y+1, x+1, "tile %d has no class", gid&^tiledGIDFlags)
+		return
+	}
+
+	if dec, ok := tile.Properties["decoration"]; ok {
+		c, ok := DecorationGlyphs[fmt.Sprint(dec)]
+		if !ok {
+			b.errorf(y+1, x+1, "unknown decoration %v", dec)
+			return
+		}
+		b.spec.DecArr[y][x] = c
+		return
+	}
+
+	if tile.Class == "" {
+		b.errorf(y+1, x+1, "tile %d has no class", gid&^tiledGIDFlags)
+		return
+	}
+	b.placeTile(x, y, tile.Class, tile.Properties)
+}
+
+// placeObject places an object of an object layer
+func (b *tiledSpecBuilder) placeObject(o *tiledObject) {
+	class := o.Class
+	if class == "" && o.GID != 0 {
+		if tile := b.m.tileOf(o.GID); tile != nil {
+			class = tile.Class
+		}
+	}
+
+	top := o.Y
+	if o.GID != 0 {
+		// tile objects are placed by their left bottom
+		top -= o.H
+	}
+	x := int(math.Floor(o.X/float64(b.m.TileWidth) + 0.5))
+	y := int(math.Floor(top/float64(b.m.TileHeight) + 0.5))
+
+	switch class {
+	case "":
+		b.errorf(y+1, x+1, "object %d has no class", o.ID)
+
+	case "pipe":
+		w := int(math.Floor(o.W/float64(b.m.TileWidth) + 0.5))
+		h := int(math.Floor(o.H/float64(b.m.TileHeight) + 0.5))
+		if w != 2 || h < 1 {
+			b.errorf(y+1, x+1, "pipe object %d should be 2 tiles wide and at least 1 tile high", o.ID)
+			return
+		}
+		b.placePipe(o, x, y, h)
+
+	default:
+		params := make(map[string]interface{})
+		for k, v := range o.Properties {
+			params[k] = v
+		}
+		if t, ok := tileTypesByName[class]; ok && t.JumpsLevel {
+			if nextLevel, ok := params["next-level"].(string); ok {
+				b.jumperNames[vector.TileID{int32(x), int32(y)}] = nextLevel
+				delete(params, "next-level")
+			}
+		}
+		b.placeTile(x, y, class, params)
+	}
+}
+
+// placePipe places a pipe of height h with its left top at (x, y)
+func (b *tiledSpecBuilder) placePipe(o *tiledObject, x, y, h int) {
+	nextLevel, _ := o.Properties["next-level"].(string)
+	eater, _ := o.Properties["eater"].(bool)
+
+	if nextLevel != "" {
+		b.placeTile(x, y, "level-pipe-left-top", nil)
+		b.placeTile(x+1, y, "level-pipe-right-top", nil)
+		b.jumperNames[vector.TileID{int32(x), int32(y)}] = nextLevel
+	} else {
+		b.placeTile(x, y, "pipe-left-top", nil)
+		b.placeTile(x+1, y, "pipe-right-top", nil)
+	}
+
+	for dy := 1; dy < h; dy++ {
+		if eater && dy == h-1 {
+			b.placeTile(x, y+dy, "pipe-left-mid-eater", nil)
+		} else {
+			b.placeTile(x, y+dy, "pipe-left-mid", nil)
+		}
+		b.placeTile(x+1, y+dy, "pipe-right-mid", nil)
+	}
+}
+
+// placeTile sets the tile at (x, y), tiles with params are given a character in legend
+func (b *tiledSpecBuilder) placeTile(x, y int, typeName string, params map[string]interface{}) {
+	if x < 0 || x >= b.m.Width || y < 0 || y >= b.m.Height {
+		b.errorf(y+1, x+1, "%s is out of map", typeName)
+		return
+	}
+	t, ok := tileTypesByName[typeName]
+	if !ok {
+		b.errorf(y+1, x+1, "unknown tile type %s", typeName)
+		return
+	}
+	for name, v := range params {
+		if err := t.checkParam(name, v); err != nil {
+			b.errorf(y+1, x+1, "%s", err)
+			return
+		}
+	}
+
+	if len(params) == 0 {
+		b.spec.LevelArr[y][x] = t.Glyph
+		return
+	}
+	glyph, ok := b.legendGlyph(TileDef{Type: typeName, Params: params})
+	if !ok {
+		b.errorf(y+1, x+1, "too many kinds of tiles with params")
+		return
+	}
+	b.spec.LevelArr[y][x] = glyph
+}
+
+// legendGlyph returns the legend character of a tile definition, allocating one if needed
+func (b *tiledSpecBuilder) legendGlyph(td TileDef) (byte, bool) {
+	// maps are printed with sorted keys
+	key := fmt.Sprintf("%s %v", td.Type, td.Params)
+	if c, ok := b.legendGlyphs[key]; ok {
+		return c, true
+	}
+	for c := byte('!'); c <= '~'; c++ {
+		if _, ok := tileTypesByGlyph[c]; ok {
+			continue
+		}
+		if _, ok := b.spec.Legend[c]; ok {
+			continue
+		}
+		b.spec.Legend[c] = td
+		b.legendGlyphs[key] = c
+		return c, true
+	}
+	return 0, false
+}
+
+// nextLevelNames lists next levels of level pipes in the order of level definition
+func (b *tiledSpecBuilder) nextLevelNames() []string {
+	var fromMap []string
+	if s := b.stringProperty("next-levels", false); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			fromMap = append(fromMap, strings.TrimSpace(name))
+		}
+	}
+
+	var names []string
+	for y, row := range b.spec.LevelArr {
+		for x := range row {
+			t := b.spec.typeAt(x, y)
+			if t == nil || !t.JumpsLevel {
+				continue
+			}
+			if name, ok := b.jumperNames[vector.TileID{int32(x), int32(y)}]; ok {
+				names = append(names, name)
+				continue
+			}
+			if len(fromMap) == 0 {
+				b.errorf(y+1, x+1, "level pipe has no next level, set its next-level or next-levels of map")
+				continue
+			}
+			names = append(names, fromMap[0])
+			fromMap = fromMap[1:]
+		}
+	}
+	if len(fromMap) > 0 {
+		b.errorf(0, 0, "map property next-levels has %d more levels than level pipes", len(fromMap))
+	}
+	return names
+}
+
+func filledArr(w, h int, c byte) [][]byte {
+	arr := make([][]byte, h)
+	for i := range arr {
+		arr[i] = bytes.Repeat([]byte{c}, w)
+	}
+	return arr
+}
@@ -0,0 +1,549 @@
+package level
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// bits of a gid used by Tiled for flipping and rotating, tiles are placed without them
+const tiledGIDFlags = 0xF0000000
+
+// tiledMap is what we need from a Tiled map, decoded from either .tmx or .tmj
+type tiledMap struct {
+	Width, Height         int // in tiles
+	TileWidth, TileHeight int // in pixels
+	Properties            map[string]interface{}
+	Tilesets              []*tiledTileset // sorted by FirstGID
+	TileLayers            [][]uint32      // gids of each tile layer row by row, in drawing order
+	Objects               []*tiledObject  // objects of all object layers
+}
+
+type tiledTileset struct {
+	FirstGID int
+	Tiles    map[int]*tiledTile // by local ID, only tiles with a class or properties are listed by Tiled
+}
+
+type tiledTile struct {
+	Class      string
+	Properties map[string]interface{}
+}
+
+type tiledObject struct {
+	ID         int
+	Class      string
+	X, Y, W, H float64 // in pixels, X and Y are left bottom for tile objects and left top for others
+	GID        uint32  // 0 if not a tile object
+	Properties map[string]interface{}
+}
+
+// tileOf returns the tile of a gid, nil if the tile has nothing set in its tileset
+func (m *tiledMap) tileOf(gid uint32) *tiledTile {
+	gid &^= tiledGIDFlags
+	for i := len(m.Tilesets) - 1; i >= 0; i-- {
+		ts := m.Tilesets[i]
+		if int(gid) >= ts.FirstGID {
+			return ts.Tiles[int(gid)-ts.FirstGID]
+		}
+	}
+	return nil
+}
+
+// loadTiledMap loads a .tmx or .tmj (also .json) map file
+func loadTiledMap(filename string) (*tiledMap, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var m *tiledMap
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".tmx":
+		m, err = decodeTMX(filename, data)
+	case ".tmj", ".json":
+		m, err = decodeTMJ(filename, data)
+	default:
+		return nil, errors.Errorf("unknown Tiled map format %s", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(m.Tilesets, func(i, j int) bool { return m.Tilesets[i].FirstGID < m.Tilesets[j].FirstGID })
+	for i, layer := range m.TileLayers {
+		if len(layer) != m.Width*m.Height {
+			return nil, errors.Errorf("tile layer %d has %d tiles but map is %dx%d", i+1, len(layer), m.Width, m.Height)
+		}
+	}
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// TMX (XML)
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type tmxMap struct {
+	Width      int           `xml:"width,attr"`
+	Height     int           `xml:"height,attr"`
+	TileWidth  int           `xml:"tilewidth,attr"`
+	TileHeight int           `xml:"tileheight,attr"`
+	Infinite   bool          `xml:"infinite,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Tilesets   []tmxTileset  `xml:"tileset"`
+	tmxGroup
+}
+
+// tmxGroup holds layers of a map or of a group layer
+type tmxGroup struct {
+	Layers       []tmxLayer       `xml:"layer"`
+	ObjectGroups []tmxObjectGroup `xml:"objectgroup"`
+	Groups       []tmxGroup       `xml:"group"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"` // multi-line strings have no value attribute
+}
+
+type tmxTileset struct {
+	FirstGID int       `xml:"firstgid,attr"`
+	Source   string    `xml:"source,attr"`
+	Tiles    []tmxTile `xml:"tile"`
+}
+
+type tmxTile struct {
+	ID         int           `xml:"id,attr"`
+	Type       string        `xml:"type,attr"` // class before Tiled 1.9
+	Class      string        `xml:"class,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxLayer struct {
+	Data struct {
+		Encoding    string `xml:"encoding,attr"`
+		Compression string `xml:"compression,attr"`
+		Tiles       []struct {
+			GID uint32 `xml:"gid,attr"`
+		} `xml:"tile"`
+		Text string `xml:",chardata"`
+	} `xml:"data"`
+}
+
+type tmxObjectGroup struct {
+	Objects []tmxObject `xml:"object"`
+}
+
+type tmxObject struct {
+	ID         int           `xml:"id,attr"`
+	Type       string        `xml:"type,attr"`
+	Class      string        `xml:"class,attr"`
+	X          float64       `xml:"x,attr"`
+	Y          float64       `xml:"y,attr"`
+	Width      float64       `xml:"width,attr"`
+	Height     float64       `xml:"height,attr"`
+	GID        uint32        `xml:"gid,attr"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+func decodeTMX(filename string, data []byte) (*tiledMap, error) {
+	var tm tmxMap
+	if err := xml.Unmarshal(data, &tm); err != nil {
+		return nil, err
+	}
+	if tm.Infinite {
+		return nil, errors.New("infinite maps are not supported")
+	}
+
+	props, err := tmxProperties(tm.Properties)
+	if err != nil {
+		return nil, err
+	}
+	m := &tiledMap{
+		Width:      tm.Width,
+		Height:     tm.Height,
+		TileWidth:  tm.TileWidth,
+		TileHeight: tm.TileHeight,
+		Properties: props,
+	}
+
+	for _, ts := range tm.Tilesets {
+		tileset, err := tmxToTileset(filename, ts)
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, tileset)
+	}
+
+	if err := m.addTMXGroup(&tm.tmxGroup); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *tiledMap) addTMXGroup(g *tmxGroup) error {
+	for _, layer := range g.Layers {
+		var gids []uint32
+		if layer.Data.Encoding == "" {
+			// one element per tile
+			for _, t := range layer.Data.Tiles {
+				gids = append(gids, t.GID)
+			}
+		} else {
+			var err error
+			gids, err = decodeTiledData(layer.Data.Encoding, layer.Data.Compression, layer.Data.Text)
+			if err != nil {
+				return err
+			}
+		}
+		m.TileLayers = append(m.TileLayers, gids)
+	}
+
+	for _, og := range g.ObjectGroups {
+		for _, o := range og.Objects {
+			props, err := tmxProperties(o.Properties)
+			if err != nil {
+				return errors.Wrapf(err, "object %d", o.ID)
+			}
+			m.Objects = append(m.Objects, &tiledObject{
+				ID:         o.ID,
+				Class:      firstNonEmpty(o.Class, o.Type),
+				X:          o.X,
+				Y:          o.Y,
+				W:          o.Width,
+				H:          o.Height,
+				GID:        o.GID,
+				Properties: props,
+			})
+		}
+	}
+
+	for i := range g.Groups {
+		if err := m.addTMXGroup(&g.Groups[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tmxToTileset(mapFile string, ts tmxTileset) (*tiledTileset, error) {
+	if ts.Source != "" {
+		return loadExternalTileset(mapFile, ts.Source, ts.FirstGID)
+	}
+
+	tileset := &tiledTileset{FirstGID: ts.FirstGID, Tiles: make(map[int]*tiledTile)}
+	for _, t := range ts.Tiles {
+		props, err := tmxProperties(t.Properties)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tile %d", t.ID)
+		}
+		tileset.Tiles[t.ID] = &tiledTile{Class: firstNonEmpty(t.Class, t.Type), Properties: props}
+	}
+	return tileset, nil
+}
+
+// tmxProperties converts properties to the types go-toml uses, so that they work as tile params
+func tmxProperties(props []tmxProperty) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for _, p := range props {
+		value := p.Value
+		if value == "" {
+			value = p.Text
+		}
+
+		var err error
+		switch p.Type {
+		case "int":
+			m[p.Name], err = strconv.ParseInt(value, 10, 64)
+		case "float":
+			m[p.Name], err = strconv.ParseFloat(value, 64)
+		case "bool":
+			m[p.Name], err = strconv.ParseBool(value)
+		default:
+			m[p.Name] = value
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "property %s", p.Name)
+		}
+	}
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// TMJ (JSON)
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type tmjMap struct {
+	Width      int           `json:"width"`
+	Height     int           `json:"height"`
+	TileWidth  int           `json:"tilewidth"`
+	TileHeight int           `json:"tileheight"`
+	Infinite   bool          `json:"infinite"`
+	Properties []tmjProperty `json:"properties"`
+	Tilesets   []tmjTileset  `json:"tilesets"`
+	Layers     []tmjLayer    `json:"layers"`
+}
+
+type tmjProperty struct {
+	Name  string      `json:"name"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type tmjTileset struct {
+	FirstGID int       `json:"firstgid"`
+	Source   string    `json:"source"`
+	Tiles    []tmjTile `json:"tiles"`
+}
+
+type tmjTile struct {
+	ID         int           `json:"id"`
+	Type       string        `json:"type"` // class before Tiled 1.9
+	Class      string        `json:"class"`
+	Properties []tmjProperty `json:"properties"`
+}
+
+type tmjLayer struct {
+	Type        string          `json:"type"`
+	Data        json.RawMessage `json:"data"` // array of gids, or a string if encoded
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Objects     []tmjObject     `json:"objects"`
+	Layers      []tmjLayer      `json:"layers"` // of group layers
+}
+
+type tmjObject struct {
+	ID         int           `json:"id"`
+	Type       string        `json:"type"`
+	Class      string        `json:"class"`
+	X          float64       `json:"x"`
+	Y          float64       `json:"y"`
+	Width      float64       `json:"width"`
+	Height     float64       `json:"height"`
+	GID        uint32        `json:"gid"`
+	Properties []tmjProperty `json:"properties"`
+}
+
+func decodeTMJ(filename string, data []byte) (*tiledMap, error) {
+	var tm tmjMap
+	if err := json.Unmarshal(data, &tm); err != nil {
+		return nil, err
+	}
+	if tm.Infinite {
+		return nil, errors.New("infinite maps are not supported")
+	}
+
+	props, err := tmjProperties(tm.Properties)
+	if err != nil {
+		return nil, err
+	}
+	m := &tiledMap{
+		Width:      tm.Width,
+		Height:     tm.Height,
+		TileWidth:  tm.TileWidth,
+		TileHeight: tm.TileHeight,
+		Properties: props,
+	}
+
+	for _, ts := range tm.Tilesets {
+		tileset, err := tmjToTileset(filename, ts)
+		if err != nil {
+			return nil, err
+		}
+		m.Tilesets = append(m.Tilesets, tileset)
+	}
+
+	if err := m.addTMJLayers(tm.Layers); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *tiledMap) addTMJLayers(layers []tmjLayer) error {
+	for _, layer := range layers {
+		switch layer.Type {
+		case "tilelayer":
+			var gids []uint32
+			if layer.Encoding == "base64" {
+				var text string
+				if err := json.Unmarshal(layer.Data, &text); err != nil {
+					return err
+				}
+				var err error
+				gids, err = decodeTiledData(layer.Encoding, layer.Compression, text)
+				if err != nil {
+					return err
+				}
+			} else if err := json.Unmarshal(layer.Data, &gids); err != nil {
+				return err
+			}
+			m.TileLayers = append(m.TileLayers, gids)
+
+		case "objectgroup":
+			for _, o := range layer.Objects {
+				props, err := tmjProperties(o.Properties)
+				if err != nil {
+					return errors.Wrapf(err, "object %d", o.ID)
+				}
+				m.Objects = append(m.Objects, &tiledObject{
+					ID:         o.ID,
+					Class:      firstNonEmpty(o.Class, o.Type),
+					X:          o.X,
+					Y:          o.Y,
+					W:          o.Width,
+					H:          o.Height,
+					GID:        o.GID,
+					Properties: props,
+				})
+			}
+
+		case "group":
+			if err := m.addTMJLayers(layer.Layers); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func tmjToTileset(mapFile string, ts tmjTileset) (*tiledTileset, error) {
+	if ts.Source != "" {
+		return loadExternalTileset(mapFile, ts.Source, ts.FirstGID)
+	}
+
+	tileset := &tiledTileset{FirstGID: ts.FirstGID, Tiles: make(map[int]*tiledTile)}
+	for _, t := range ts.Tiles {
+		props, err := tmjProperties(t.Properties)
+		if err != nil {
+			return nil, errors.Wrapf(err, "tile %d", t.ID)
+		}
+		tileset.Tiles[t.ID] = &tiledTile{Class: firstNonEmpty(t.Class, t.Type), Properties: props}
+	}
+	return tileset, nil
+}
+
+// tmjProperties converts properties to the types go-toml uses, so that they work as tile params
+func tmjProperties(props []tmjProperty) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for _, p := range props {
+		if p.Type != "int" {
+			m[p.Name] = p.Value
+			continue
+		}
+		v, ok := p.Value.(float64)
+		if !ok {
+			return nil, errors.Errorf("property %s should be an int", p.Name)
+		}
+		m[p.Name] = int64(v)
+	}
+	return m, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// loadExternalTileset loads a .tsx or .tsj (also .json) tileset file, source is relative to the map file
+func loadExternalTileset(mapFile, source string, firstGID int) (*tiledTileset, error) {
+	filename := filepath.Join(filepath.Dir(mapFile), source)
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var tileset *tiledTileset
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".tsx":
+		var ts tmxTileset
+		if err := xml.Unmarshal(data, &ts); err != nil {
+			return nil, errors.Wrapf(err, "tileset %s", source)
+		}
+		ts.Source = ""
+		tileset, err = tmxToTileset(filename, ts)
+	case ".tsj", ".json":
+		var ts tmjTileset
+		if err := json.Unmarshal(data, &ts); err != nil {
+			return nil, errors.Wrapf(err, "tileset %s", source)
+		}
+		ts.Source = ""
+		tileset, err = tmjToTileset(filename, ts)
+	default:
+		return nil, errors.Errorf("unknown Tiled tileset format %s", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "tileset %s", source)
+	}
+
+	// the first gid is only known by the map
+	tileset.FirstGID = firstGID
+	return tileset, nil
+}
+
+// decodeTiledData decodes the gids of an encoded tile layer
+func decodeTiledData(encoding, compression, text string) ([]uint32, error) {
+	switch encoding {
+	case "csv":
+		var gids []uint32
+		for _, s := range strings.Split(strings.TrimSpace(text), ",") {
+			gid, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return nil, errors.Wrap(err, "bad csv tile data")
+			}
+			gids = append(gids, uint32(gid))
+		}
+		return gids, nil
+
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, errors.Wrap(err, "bad base64 tile data")
+		}
+
+		var r io.Reader = bytes.NewReader(data)
+		switch compression {
+		case "":
+		case "zlib":
+			r, err = zlib.NewReader(r)
+		case "gzip":
+			r, err = gzip.NewReader(r)
+		default:
+			return nil, errors.Errorf("tile data compression %s is not supported", compression)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "bad compressed tile data")
+		}
+		data, err = ioutil.ReadAll(r)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad compressed tile data")
+		}
+
+		gids := make([]uint32, len(data)/4)
+		for i := range gids {
+			gids[i] = binary.LittleEndian.Uint32(data[i*4:])
+		}
+		return gids, nil
+	}
+	return nil, errors.Errorf("tile data encoding %s is not supported", encoding)
+}
+
+func firstNonEmpty(strs ...string) string {
+	for _, s := range strs {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+package level_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zenja/mario/level"
+)
+
+const testTMX = `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="6" height="5" tilewidth="50" tileheight="50" infinite="0">
+ <properties>
+  <property name="name" value="tiled"/>
+  <property name="bg-file" value="assets/bg-0.png"/>
+  <property name="bg-color-rgb" value="204, 237, 255"/>
+ </properties>
+ <tileset firstgid="1" name="tiles" tilewidth="50" tileheight="50" tilecount="4" columns="4">
+  <tile id="0" class="grass-ground-mid"/>
+  <tile id="1" class="coin-box">
+   <properties>
+    <property name="coins" type="int" value="5"/>
+   </properties>
+  </tile>
+  <tile id="2">
+   <properties>
+    <property name="decoration" value="grass"/>
+   </properties>
+  </tile>
+ </tileset>
+ <layer id="1" name="ground" width="6" height="5">
+  <data encoding="csv">
+0,0,0,0,0,0,
+0,0,0,0,0,0,
+0,2,0,0,0,0,
+0,0,0,0,0,0,
+1,1,1,1,1,1
+</data>
+ </layer>
+ <layer id="2" name="decorations" width="6" height="5">
+  <data encoding="csv">
+0,0,0,0,0,0,
+0,0,0,0,0,0,
+0,0,0,0,0,0,
+3,0,0,0,0,0,
+0,0,0,0,0,0
+</data>
+ </layer>
+ <objectgroup id="3" name="entities">
+  <object id="1" class="hero" x="0" y="150" width="50" height="50"/>
+  <object id="2" class="pipe" x="150" y="100" width="100" height="100">
+   <properties>
+    <property name="next-level" value="level-next"/>
+   </properties>
+  </object>
+  <object id="3" class="mushroom-enemy" x="250" y="150" width="50" height="50"/>
+ </objectgroup>
+</map>
+`
+
+const testTMJ = `{
+  "width": 4, "height": 3, "tilewidth": 50, "tileheight": 50, "infinite": false,
+  "properties": [
+    {"name": "bg-file", "type": "string", "value": "assets/bg-0.png"},
+    {"name": "next-levels", "type": "string", "value": "level-a, level-b"}
+  ],
+  "tilesets": [{"firstgid": 1, "source": "tiles.tsj"}],
+  "layers": [
+    {"type": "group", "layers": [
+      {"type": "tilelayer", "data": [3,4,3,4, 0,0,0,0, 1,1,1,1]}
+    ]},
+    {"type": "objectgroup", "objects": [
+      {"id": 1, "gid": 2, "x": 50, "y": 100, "width": 50, "height": 50}
+    ]}
+  ]
+}
+`
+
+const testTSJ = `{
+  "tiles": [
+    {"id": 0, "class": "brick-red"},
+    {"id": 1, "class": "hero"},
+    {"id": 2, "class": "level-pipe-left-top"},
+    {"id": 3, "class": "level-pipe-right-top"}
+  ]
+}
+`
+
+func TestParseTiledLevelSpecTMX(t *testing.T) {
+	dir := writeTempFiles(t, map[string]string{"tiled.tmx": testTMX})
+	defer os.RemoveAll(dir)
+
+	spec, err := level.ParseLevelFile(filepath.Join(dir, "tiled.tmx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Name != "tiled" || spec.BgFilename != "assets/bg-0.png" || spec.BgColor.R != 204 {
+		t.Errorf("unexpected map properties: %s %s %v", spec.Name, spec.BgFilename, spec.BgColor)
+	}
+	if len(spec.NextLevelNames) != 1 || spec.NextLevelNames[0] != "level-next" {
+		t.Errorf("expected next levels [level-next] but was %v", spec.NextLevelNames)
+	}
+
+	// the coin box has params, so it is given a character in legend
+	coinBox := spec.LevelArr[2][1]
+	wantRows := []string{
+		"......",
+		"......",
+		"." + string(coinBox) + ".{}.",
+		"H..[]1",
+		"GGGGGG",
+	}
+	for y, want := range wantRows {
+		if string(spec.LevelArr[y]) != want {
+			t.Errorf("expected row %d to be %q but was %q", y, want, spec.LevelArr[y])
+		}
+	}
+	if td, ok := spec.TileAt(1, 2); !ok || td.Type != "coin-box" || td.IntParam("coins") != 5 {
+		t.Errorf("expected coin box with 5 coins but was %v", td)
+	}
+	if spec.DecArr[3][0] != '1' {
+		t.Errorf("expected grass decoration but was '%c'", spec.DecArr[3][0])
+	}
+
+	if _, err := level.BuildLevel(spec, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseTiledLevelSpecTMJ(t *testing.T) {
+	dir := writeTempFiles(t, map[string]string{
+		"tiled.tmj": testTMJ,
+		"tiles.tsj": testTSJ,
+	})
+	defer os.RemoveAll(dir)
+
+	specs, err := level.LoadLevelSpecs(dir)
+	spec, ok := specs["tiled"]
+	if !ok {
+		t.Fatalf("expected level named after the file but was %v (%v)", specs, err)
+	}
+
+	wantRows := []string{
+		"{}{}",
+		".H..",
+		"DDDD",
+	}
+	for y, want := range wantRows {
+		if string(spec.LevelArr[y]) != want {
+			t.Errorf("expected row %d to be %q but was %q", y, want, spec.LevelArr[y])
+		}
+	}
+	if len(spec.NextLevelNames) != 2 || spec.NextLevelNames[0] != "level-a" || spec.NextLevelNames[1] != "level-b" {
+		t.Errorf("expected next levels [level-a level-b] but was %v", spec.NextLevelNames)
+	}
+}
+
+func TestParseTiledLevelSpecErrors(t *testing.T) {
+	dir := writeTempFiles(t, map[string]string{"bad.tmj": `{
+  "width": 2, "height": 2, "tilewidth": 50, "tileheight": 50,
+  "properties": [{"name": "bg-file", "type": "string", "value": "assets/bg-0.png"}],
+  "tilesets": [{"firstgid": 1, "tiles": [{"id": 0, "class": "hero"}]}],
+  "layers": [
+    {"type": "tilelayer", "data": [1,0, 0,0]},
+    {"type": "objectgroup", "objects": [
+      {"id": 7, "class": "superhero", "x": 50, "y": 50, "width": 50, "height": 50}
+    ]}
+  ]
+}
+`})
+	defer os.RemoveAll(dir)
+
+	_, err := level.ParseLevelFile(filepath.Join(dir, "bad.tmj"))
+	errs, ok := err.(level.ParseErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expected 1 error but was %v", err)
+	}
+	if errs[0].Row != 2 || errs[0].Col != 2 {
+		t.Errorf("expected error at 2:2 but was %d:%d", errs[0].Row, errs[0].Col)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// writeTempFiles writes files into a new temp dir and returns the dir
+func writeTempFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "level")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,53 @@
+package level
+
+// TimeLeft returns milliseconds left before hero runs out of time, false if the level has no time limit
+func (l *Level) TimeLeft() (int, bool) {
+	if l.Spec.TimeLimit <= 0 {
+		return 0, false
+	}
+	return l.timeLeftMs, true
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (l *Level) resetTimer() {
+	l.timeLeftMs = l.Spec.TimeLimit * 1000
+	l.timerLastTicks = 0
+}
+
+// updateTimer counts down time left with level time, and kills hero when time is out
+func (l *Level) updateTimer(ticks uint32) {
+	if l.Spec.TimeLimit <= 0 {
+		return
+	}
+	elapsed := 0
+	if l.timerLastTicks != 0 {
+		elapsed = int(ticks - l.timerLastTicks)
+	}
+	l.timerLastTicks = ticks
+	if l.isTimerPaused() || l.timeLeftMs <= 0 {
+		return
+	}
+
+	l.timeLeftMs -= elapsed
+	if l.timeLeftMs <= 0 {
+		l.timeLeftMs = 0
+		l.TheHero.Kill(l)
+	}
+}
+
+// isTimerPaused tells if time stops, that is when hero can't move (e.g. going through a pipe, dying or completing
+// the level) or the screen is fading
+func (l *Level) isTimerPaused() bool {
+	if l.TheHero.isDead || l.TheHero.disabled {
+		return true
+	}
+	for e := l.effects.Front(); e != nil; e = e.Next() {
+		if fade, ok := e.Value.(*screenFadeEffect); ok && !fade.Finished() {
+			return true
+		}
+	}
+	return false
+}
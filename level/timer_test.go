@@ -0,0 +1,54 @@
+package level_test
+
+import (
+	"testing"
+
+	"github.com/zenja/mario/clock"
+)
+
+func TestTimeOutKillsHero(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	spec := newTestSpec(
+		"..........",
+		".H........",
+		"BBBBBBBBBB",
+	)
+	spec.TimeLimit = 2
+	l := mustBuildLevel(t, spec, clk)
+	l.Init()
+
+	// time doesn't run while the screen fades in
+	runFrames(l, clk, 60)
+	if left, _ := l.TimeLeft(); left != 2000 {
+		t.Errorf("expected timer to be paused while fading in but %d ms were left", left)
+	}
+
+	runFrames(l, clk, 100)
+	if l.TheHero.IsDead() {
+		t.Fatal("expected hero to be alive before time is out")
+	}
+	runFrames(l, clk, 100)
+	if !l.TheHero.IsDead() {
+		t.Errorf("expected hero to be killed when time is out")
+	}
+	if left, ok := l.TimeLeft(); !ok || left != 0 {
+		t.Errorf("expected no time left but was %d", left)
+	}
+}
+
+func TestNoTimeLimit(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	l := mustBuildLevel(t, newTestSpec(
+		".H........",
+		"BBBBBBBBBB",
+	), clk)
+	l.Init()
+	runFrames(l, clk, 300)
+
+	if _, ok := l.TimeLeft(); ok {
+		t.Error("expected no timer without a time limit")
+	}
+	if l.TheHero.IsDead() {
+		t.Error("expected hero to stay alive without a time limit")
+	}
+}
@@ -10,6 +10,9 @@ type volatileObject interface {
 	Object
 
 	IsDead() bool
+
+	ID() EntityID
+	setID(id EntityID)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
@@ -29,6 +32,8 @@ const (
 )
 
 type fireball struct {
+	id EntityID
+
 	res0    graphic.Resource
 	res1    graphic.Resource
 	res2    graphic.Resource
@@ -40,6 +45,7 @@ type fireball struct {
 	lastTicks  uint32
 	levelRect  sdl.Rect
 	velocity   vector.Vec2D
+	subPixel   vector.Vec2D
 	isDead     bool
 }
 
@@ -98,7 +104,7 @@ func (f *fireball) Update(ticks uint32, level *Level) {
 	f.velocity.Add(gravity)
 
 	maxVel := vector.Vec2D{400, 200}
-	velStep := CalcVelocityStep(f.velocity, ticks, f.lastTicks, &maxVel)
+	velStep := CalcVelocityStep(f.velocity, ticks, f.lastTicks, &maxVel, &f.subPixel)
 	f.levelRect.X += velStep.X
 	f.levelRect.Y += velStep.Y
 
@@ -157,6 +163,14 @@ func (f *fireball) IsDead() bool {
 	return f.isDead
 }
 
+func (f *fireball) ID() EntityID {
+	return f.id
+}
+
+func (f *fireball) setID(id EntityID) {
+	f.id = id
+}
+
 func (f *fireball) boom(level *Level, ticks uint32) {
 	f.isDead = true
 	boomStartPos := vector.Vec2D{
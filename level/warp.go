@@ -0,0 +1,121 @@
+package level
+
+import (
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/zenja/mario/audio"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/vector"
+)
+
+// name of the tile type marking where hero comes into a level through a pipe
+// An entry is put right above the left top of a pipe and is named with a [legend], like:
+//
+//	[legend]
+//	"a" = { type = "entry", name = "from-secret" }
+//
+// a level pipe of another level then targets it with "level-0:from-secret" in its next-levels
+const EntryTileType = "entry"
+
+func init() {
+	RegisterTileType(TileType{
+		Name:   EntryTileType,
+		Glyph:  '*',
+		Obst:   not_obst,
+		Params: map[string]interface{}{"name": ""},
+	})
+}
+
+// ParseWarpTarget splits a next level like "level-0:from-secret" into level name and entry name
+// The entry name is empty if the target is just a level, where hero starts at its 'H'
+func ParseWarpTarget(target string) (levelName, entry string) {
+	if i := strings.LastIndex(target, ":"); i >= 0 {
+		return target[:i], target[i+1:]
+	}
+	return target, ""
+}
+
+// EntryTile returns the tile of a named entry, false if there is no such entry
+func (spec *LevelSpec) EntryTile(name string) (vector.TileID, bool) {
+	for y, row := range spec.LevelArr {
+		for x := range row {
+			td, ok := spec.TileAt(x, y)
+			if ok && td.Type == EntryTileType && td.StringParam("name") == name {
+				return vector.TileID{int32(x), int32(y)}, true
+			}
+		}
+	}
+	return vector.TileID{}, false
+}
+
+// Switch leaves level l for a next level target like "level" or "level:entry" and returns the level entered
+// A persistent level left is kept in cache and entered again as it was, while other levels are built afresh;
+// the level being left is never taken from cache, so a level jumping to itself starts over
+func Switch(cache map[string]*Level, specs map[string]*LevelSpec, l *Level, target string) (*Level, error) {
+	levelName, entry := ParseWarpTarget(target)
+
+	nextLevel, ok := cache[levelName]
+	if ok {
+		delete(cache, levelName)
+		nextLevel.Resume()
+		// the switch which left the level is done
+		nextLevel.nextLevelName = ""
+		nextLevel.completed = false
+	} else {
+		spec, ok := specs[levelName]
+		if !ok {
+			return nil, errors.Errorf("next level not found: %s", levelName)
+		}
+		var err error
+		nextLevel, err = BuildLevel(spec, l.clock)
+		if err != nil {
+			return nil, err
+		}
+		if l.rewinds != nil {
+			nextLevel.EnableRewind()
+		}
+	}
+
+	if l.Spec.Persistent && levelName != l.Spec.Name {
+		l.Suspend()
+		cache[l.Spec.Name] = l
+	}
+
+	// hero, coins and score keep unchanged
+	nextLevel.TheHero = l.TheHero
+	nextLevel.Coins = l.Coins
+	nextLevel.Score = l.Score
+
+	nextLevel.InitAtEntry(entry)
+	return nextLevel, nil
+}
+
+// InitAtEntry inits the level with hero coming out of the pipe under a named entry
+// An empty entry means the level start, the same as Init
+func (l *Level) InitAtEntry(entry string) {
+	l.Init()
+	if len(entry) == 0 {
+		return
+	}
+
+	tid, ok := l.Spec.EntryTile(entry)
+	if !ok {
+		// entries of next levels are checked when loading levels, but the level may have been edited since
+		log.Printf("entry %s not found in level %s, starting from level start", entry, l.Spec.Name)
+		return
+	}
+
+	// stand in the middle of the 2 tiles wide pipe
+	h := l.TheHero
+	rect := h.GetRect()
+	h.LiveAndResetPos(vector.Pos{
+		tid.X*graphic.TILE_SIZE + graphic.TILE_SIZE - rect.W/2,
+		(tid.Y+1)*graphic.TILE_SIZE - rect.H,
+	})
+
+	h.Disable()
+	l.AddEffect(NewHeroOutOfPipeEffect(h, l.Ticks(), l.newHook(hook_enable_hero)))
+	audio.PlaySound(audio.SOUND_PIPE)
+}
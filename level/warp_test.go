@@ -0,0 +1,90 @@
+package level_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenja/mario/clock"
+	"github.com/zenja/mario/graphic"
+	"github.com/zenja/mario/level"
+)
+
+func TestParseWarpTarget(t *testing.T) {
+	cases := []struct {
+		target, levelName, entry string
+	}{
+		{"level-0", "level-0", ""},
+		{"level-0:from-secret", "level-0", "from-secret"},
+		{"level-0.secret-0:", "level-0.secret-0", ""},
+	}
+	for _, c := range cases {
+		levelName, entry := level.ParseWarpTarget(c.target)
+		if levelName != c.levelName || entry != c.entry {
+			t.Errorf("expected %q to be level %q entry %q but was %q %q",
+				c.target, c.levelName, c.entry, levelName, entry)
+		}
+	}
+}
+
+func TestInitAtEntry(t *testing.T) {
+	clk := clock.NewManualClock(1)
+	spec := newTestSpec(
+		"H.a...",
+		"......",
+		"......",
+		"BBBBBB",
+	)
+	spec.Legend = map[byte]level.TileDef{
+		'a': {Type: level.EntryTileType, Params: map[string]interface{}{"name": "up"}},
+	}
+	l := mustBuildLevel(t, spec, clk)
+	l.InitAtEntry("up")
+
+	// centered over the 2 tiles wide pipe below the entry
+	start := l.TheHero.GetRect()
+	if start.X+start.W/2 != 3*graphic.TILE_SIZE || start.Y+start.H != graphic.TILE_SIZE {
+		t.Fatalf("expected hero to come out at bottom middle of entry but was at %v", start)
+	}
+
+	// hero doesn't fall while coming out of pipe
+	runFrames(l, clk, 10)
+	if l.TheHero.GetRect() != start {
+		t.Errorf("expected hero not to move while coming out of pipe but was at %v", l.TheHero.GetRect())
+	}
+
+	runFrames(l, clk, 300)
+	if r := l.TheHero.GetRect(); r.Y+r.H != 3*graphic.TILE_SIZE {
+		t.Errorf("expected hero to fall onto ground after coming out of pipe but was at %v", r)
+	}
+}
+
+func TestLoadLevelSpecsChecksEntries(t *testing.T) {
+	level0 := `[basic]
+name = "level-0"
+
+[transfer]
+next-levels = ["level-1:nowhere"]
+
+[graphic]
+bg-file = "assets/bg-0.png"
+bg-color-rgb = "0, 0, 0"
+
+[level]
+def = """
+.H{}
+BBBB"""
+
+dec-def = """
+....
+...."""
+`
+	level1 := strings.NewReplacer(`"level-0"`, `"level-1"`, "level-1:nowhere", "level-0").Replace(level0)
+	dir := writeTempFiles(t, map[string]string{"level0.toml": level0, "level1.toml": level1})
+	defer os.RemoveAll(dir)
+
+	_, err := level.LoadLevelSpecs(dir)
+	if err == nil || !strings.Contains(err.Error(), "entry nowhere not found in next level level-1") {
+		t.Errorf("expected missing entry reported but was %v", err)
+	}
+}
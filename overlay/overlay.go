@@ -64,3 +64,44 @@ func (co *CoinsOverlay) Draw(level *level.Level, ticks uint32) {
 	color := sdl.Color{255, 255, 255, 0}
 	graphic.DrawText(fmt.Sprintf("Coins: %d", level.Coins), pos, color)
 }
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// ScoreOverlay
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type ScoreOverlay struct{}
+
+func (so *ScoreOverlay) Draw(level *level.Level, ticks uint32) {
+	pos := vector.Pos{graphic.SCREEN_WIDTH/2 - 50, 80}
+	color := sdl.Color{255, 255, 255, 0}
+	graphic.DrawText(fmt.Sprintf("Score: %d", level.Score), pos, color)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// TimerOverlay
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// time left in seconds from which the timer warns
+const time_warning_seconds = 100
+
+// TimerOverlay shows time left of levels with a time limit, it turns red and blinks when time is low
+type TimerOverlay struct{}
+
+func (to *TimerOverlay) Draw(level *level.Level, ticks uint32) {
+	ms, ok := level.TimeLeft()
+	if !ok {
+		return
+	}
+	// round up, so that time is out exactly when 0 is shown
+	seconds := (ms + 999) / 1000
+
+	pos := vector.Pos{graphic.SCREEN_WIDTH - 150, 80}
+	color := sdl.Color{255, 255, 255, 0}
+	if seconds <= time_warning_seconds {
+		color = sdl.Color{255, 60, 60, 0}
+		if seconds > 0 && ticks/250%2 == 1 {
+			return
+		}
+	}
+	graphic.DrawText(fmt.Sprintf("Time: %d", seconds), pos, color)
+}
@@ -0,0 +1,244 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/tools/container/intsets"
+)
+
+// FORMAT_VERSION is bumped whenever the file format changes
+const FORMAT_VERSION = 1
+
+// events are stored as bits of an uint64 mask
+const maxEvent = 63
+
+var magic = [4]byte{'M', 'R', 'P', 'L'}
+
+// Header is what needs to be known to start replaying
+type Header struct {
+	Version   uint16
+	LevelName string
+	HeroGrade int
+	HeroLives int
+	Coins     int
+}
+
+// A replay file looks like:
+//
+//     magic "MRPL" | version (uint16, little endian) | header fields (varints, level name is length + bytes)
+//     then a list of (event mask, number of repeats) pairs, both uvarints, until EOF
+//
+// There is one event set per simulation step, and consecutive identical sets are stored only once,
+// so holding a key for a long time costs only a few bytes
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Recorder
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Recorder writes event sets into a replay file
+// Write errors are kept by the buffered writer and reported by Close()
+type Recorder struct {
+	w      *bufio.Writer
+	closer io.Closer
+
+	// the pending run of identical event masks
+	lastMask uint64
+	repeats  uint64
+}
+
+func CreateRecorder(filename string, header Header) (*Recorder, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create replay file")
+	}
+	rec := NewRecorder(f, header)
+	rec.closer = f
+	return rec, nil
+}
+
+// NewRecorder writes header to w, the events should be recorded by Record() afterwards
+func NewRecorder(w io.Writer, header Header) *Recorder {
+	rec := &Recorder{w: bufio.NewWriter(w)}
+
+	rec.w.Write(magic[:])
+	binary.Write(rec.w, binary.LittleEndian, uint16(FORMAT_VERSION))
+	rec.writeUvarint(uint64(len(header.LevelName)))
+	rec.w.WriteString(header.LevelName)
+	rec.writeVarint(int64(header.HeroGrade))
+	rec.writeVarint(int64(header.HeroLives))
+	rec.writeVarint(int64(header.Coins))
+
+	return rec
+}
+
+// Record records the event set of one simulation step
+func (rec *Recorder) Record(events *intsets.Sparse) error {
+	mask, err := toMask(events)
+	if err != nil {
+		return err
+	}
+
+	if rec.repeats > 0 && mask == rec.lastMask {
+		rec.repeats++
+		return nil
+	}
+	rec.flushRun()
+	rec.lastMask = mask
+	rec.repeats = 1
+	return nil
+}
+
+// Close writes everything pending, the recorder cannot be used afterwards
+func (rec *Recorder) Close() error {
+	rec.flushRun()
+	if err := rec.w.Flush(); err != nil {
+		return errors.Wrap(err, "failed to flush replay file")
+	}
+	if rec.closer != nil {
+		return rec.closer.Close()
+	}
+	return nil
+}
+
+func (rec *Recorder) flushRun() {
+	if rec.repeats == 0 {
+		return
+	}
+	rec.writeUvarint(rec.lastMask)
+	rec.writeUvarint(rec.repeats)
+	rec.repeats = 0
+}
+
+func (rec *Recorder) writeUvarint(x uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], x)
+	rec.w.Write(buf[:n])
+}
+
+func (rec *Recorder) writeVarint(x int64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(buf[:], x)
+	rec.w.Write(buf[:n])
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Player
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type Player struct {
+	header Header
+	r      *bufio.Reader
+	closer io.Closer
+
+	// current run of identical event masks
+	mask    uint64
+	repeats uint64
+}
+
+func OpenPlayer(filename string) (*Player, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open replay file")
+	}
+	p, err := NewPlayer(f)
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "failed to load replay file %s", filename)
+	}
+	p.closer = f
+	return p, nil
+}
+
+// NewPlayer reads the header from r, the events can be read by Next() afterwards
+func NewPlayer(r io.Reader) (*Player, error) {
+	p := &Player{r: bufio.NewReader(r)}
+
+	var m [4]byte
+	if _, err := io.ReadFull(p.r, m[:]); err != nil || m != magic {
+		return nil, errors.New("not a replay file")
+	}
+	if err := binary.Read(p.r, binary.LittleEndian, &p.header.Version); err != nil {
+		return nil, errors.Wrap(err, "failed to read replay version")
+	}
+	if p.header.Version != FORMAT_VERSION {
+		return nil, errors.Errorf("unsupported replay version %d, expected %d", p.header.Version, FORMAT_VERSION)
+	}
+
+	nameLen, err := binary.ReadUvarint(p.r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read replay header")
+	}
+	name := make([]byte, nameLen)
+	if _, err := io.ReadFull(p.r, name); err != nil {
+		return nil, errors.Wrap(err, "failed to read replay header")
+	}
+	p.header.LevelName = string(name)
+
+	for _, field := range []*int{&p.header.HeroGrade, &p.header.HeroLives, &p.header.Coins} {
+		v, err := binary.ReadVarint(p.r)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read replay header")
+		}
+		*field = int(v)
+	}
+
+	return p, nil
+}
+
+func (p *Player) Header() Header {
+	return p.header
+}
+
+// Next returns the event set of next simulation step, or false if the replay is finished
+func (p *Player) Next() (*intsets.Sparse, bool) {
+	if p.repeats == 0 {
+		mask, err := binary.ReadUvarint(p.r)
+		if err != nil {
+			return nil, false
+		}
+		repeats, err := binary.ReadUvarint(p.r)
+		if err != nil || repeats == 0 {
+			return nil, false
+		}
+		p.mask = mask
+		p.repeats = repeats
+	}
+	p.repeats--
+	return fromMask(p.mask), true
+}
+
+func (p *Player) Close() error {
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper functions
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func toMask(events *intsets.Sparse) (uint64, error) {
+	var mask uint64
+	for _, e := range events.AppendTo(nil) {
+		if e < 0 || e > maxEvent {
+			return 0, errors.Errorf("event %d cannot be recorded, must be in [0, %d]", e, maxEvent)
+		}
+		mask |= 1 << uint(e)
+	}
+	return mask, nil
+}
+
+func fromMask(mask uint64) *intsets.Sparse {
+	var events intsets.Sparse
+	for e := 0; e <= maxEvent; e++ {
+		if mask&(1<<uint(e)) != 0 {
+			events.Insert(e)
+		}
+	}
+	return &events
+}
@@ -0,0 +1,67 @@
+package replay_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zenja/mario/replay"
+	"golang.org/x/tools/container/intsets"
+)
+
+func TestRecordAndPlay(t *testing.T) {
+	header := replay.Header{
+		LevelName: "level-0",
+		HeroGrade: 2,
+		HeroLives: 3,
+		Coins:     12,
+	}
+
+	var steps []*intsets.Sparse
+	for i := 0; i < 100; i++ {
+		var events intsets.Sparse
+		// hold 1 all the time, and 4 in the middle
+		events.Insert(1)
+		if i >= 40 && i < 60 {
+			events.Insert(4)
+		}
+		steps = append(steps, &events)
+	}
+
+	var buf bytes.Buffer
+	rec := replay.NewRecorder(&buf, header)
+	for _, events := range steps {
+		if err := rec.Record(events); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := replay.NewPlayer(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.Version = replay.FORMAT_VERSION
+	if p.Header() != header {
+		t.Errorf("expected header %v but was %v", header, p.Header())
+	}
+	for i, expected := range steps {
+		actual, ok := p.Next()
+		if !ok {
+			t.Fatalf("replay finished too early at step %d", i)
+		}
+		if !actual.Equals(expected) {
+			t.Errorf("step %d: expected events %s but was %s", i, expected, actual)
+		}
+	}
+	if _, ok := p.Next(); ok {
+		t.Error("expected replay to be finished")
+	}
+}
+
+func TestNotAReplayFile(t *testing.T) {
+	if _, err := replay.NewPlayer(bytes.NewReader([]byte("[basic]"))); err == nil {
+		t.Error("expected an error for a non-replay file")
+	}
+}
@@ -0,0 +1,199 @@
+package save
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// FORMAT_VERSION is bumped whenever the file format changes, older files are migrated when loaded
+const FORMAT_VERSION = 1
+
+// number of profile slots, each has its own save file
+const NUM_SLOTS = 3
+
+// Progress is what is kept of a campaign between runs of the game
+type Progress struct {
+	Version int `json:"version"`
+
+	// levels ever entered, they can be chosen in level select
+	UnlockedLevels []string `json:"unlocked-levels"`
+
+	// where to continue, empty if no game has been played
+	CurrentLevel string `json:"current-level,omitempty"`
+
+	// hero state when entering current level
+	Lives     int `json:"lives"`
+	Coins     int `json:"coins"`
+	Score     int `json:"score"`
+	HeroGrade int `json:"hero-grade"`
+
+	// the least level time in milliseconds taken from entering a level to reaching its goal, by level name
+	BestTimes map[string]uint32 `json:"best-times"`
+
+	// secret levels found
+	Secrets []string `json:"secrets"`
+}
+
+func NewProgress() *Progress {
+	return &Progress{
+		Version:   FORMAT_VERSION,
+		BestTimes: make(map[string]uint32),
+	}
+}
+
+// IsUnlocked tells if the level has been entered before
+func (p *Progress) IsUnlocked(levelName string) bool {
+	return contains(p.UnlockedLevels, levelName)
+}
+
+func (p *Progress) Unlock(levelName string) {
+	p.UnlockedLevels = addSorted(p.UnlockedLevels, levelName)
+}
+
+// RecordTime keeps the time taken to complete a level if it is the best one, and tells if it is
+func (p *Progress) RecordTime(levelName string, ms uint32) bool {
+	if best, ok := p.BestTimes[levelName]; ok && best <= ms {
+		return false
+	}
+	p.BestTimes[levelName] = ms
+	return true
+}
+
+func (p *Progress) CollectSecret(levelName string) {
+	p.Secrets = addSorted(p.Secrets, levelName)
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Store
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Store keeps save files of all profile slots in a directory
+type Store struct {
+	dir string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// DefaultDir returns the directory of save files under the user config dir, e.g. ~/.config/mario/saves
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find user config dir")
+	}
+	return filepath.Join(configDir, "mario", "saves"), nil
+}
+
+// Load reads progress of a slot, a slot never saved gives new progress
+func (s *Store) Load(slot int) (*Progress, error) {
+	filename, err := s.filename(slot)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return NewProgress(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read save file")
+	}
+	p, err := decode(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load save file %s", filename)
+	}
+	return p, nil
+}
+
+// Save writes progress of a slot, the old save file is kept if writing fails
+func (s *Store) Save(slot int, p *Progress) error {
+	filename, err := s.filename(slot)
+	if err != nil {
+		return err
+	}
+	p.Version = FORMAT_VERSION
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode progress")
+	}
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create save dir")
+	}
+	// write to a temporary file first, so that a crash never leaves a broken save file
+	tmp := filename + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.Wrap(err, "failed to write save file")
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to write save file")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Private helpers
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *Store) filename(slot int) (string, error) {
+	if slot < 1 || slot > NUM_SLOTS {
+		return "", errors.Errorf("save slot should be in [1, %d] but was %d", NUM_SLOTS, slot)
+	}
+	return filepath.Join(s.dir, fmt.Sprintf("slot-%d.json", slot)), nil
+}
+
+// decode parses a save file of any version up to FORMAT_VERSION
+func decode(data []byte) (*Progress, error) {
+	p := NewProgress()
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, errors.Wrap(err, "malformed save file")
+	}
+	if p.Version < 1 || p.Version > FORMAT_VERSION {
+		return nil, errors.Errorf("unsupported save file version %d", p.Version)
+	}
+	// migrations from older versions go here
+
+	if p.BestTimes == nil {
+		p.BestTimes = make(map[string]uint32)
+	}
+	// names are looked up by binary search, but the file may have been edited by hand
+	p.UnlockedLevels = sortUnique(p.UnlockedLevels)
+	p.Secrets = sortUnique(p.Secrets)
+	return p, nil
+}
+
+// sortUnique sorts names and drops duplicates
+func sortUnique(names []string) []string {
+	sort.Strings(names)
+	var unique []string
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return unique
+}
+
+func contains(names []string, name string) bool {
+	i := sort.SearchStrings(names, name)
+	return i < len(names) && names[i] == name
+}
+
+// addSorted inserts name into a sorted list if it is not there
+func addSorted(names []string, name string) []string {
+	i := sort.SearchStrings(names, name)
+	if i < len(names) && names[i] == name {
+		return names
+	}
+	names = append(names, "")
+	copy(names[i+1:], names[i:])
+	names[i] = name
+	return names
+}
@@ -0,0 +1,107 @@
+package save_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zenja/mario/save"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	store := save.NewStore(tempDir(t))
+
+	p := save.NewProgress()
+	p.Unlock("level-1")
+	p.Unlock("level-0")
+	p.Unlock("level-1")
+	p.CollectSecret("level-0.secret-0")
+	p.CurrentLevel = "level-1"
+	p.Lives = 4
+	p.Coins = 12
+	p.Score = 3400
+	p.HeroGrade = 2
+	if !p.RecordTime("level-0", 90000) || p.RecordTime("level-0", 95000) || !p.RecordTime("level-0", 80000) {
+		t.Error("expected only better times to be recorded")
+	}
+
+	if err := store.Save(2, p); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, p) {
+		t.Errorf("expected %+v but was %+v", p, loaded)
+	}
+	if !loaded.IsUnlocked("level-0") || loaded.IsUnlocked("level-2") {
+		t.Errorf("unexpected unlocked levels %v", loaded.UnlockedLevels)
+	}
+	if loaded.BestTimes["level-0"] != 80000 {
+		t.Errorf("expected best time 80000 but was %d", loaded.BestTimes["level-0"])
+	}
+
+	// other slots are untouched
+	empty, err := store.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.CurrentLevel) > 0 || len(empty.UnlockedLevels) > 0 {
+		t.Errorf("expected new progress in an unused slot but was %+v", empty)
+	}
+}
+
+func TestLoadUnsortedSaveFile(t *testing.T) {
+	dir := tempDir(t)
+	store := save.NewStore(dir)
+
+	content := `{"version": 1, "unlocked-levels": ["level-1", "level-0", "level-1"], "secrets": ["b", "a"]}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "slot-1.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := store.Load(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.IsUnlocked("level-0") || !p.IsUnlocked("level-1") {
+		t.Errorf("expected levels of save file unlocked but were %v", p.UnlockedLevels)
+	}
+	p.Unlock("level-0")
+	p.CollectSecret("a")
+	if want := []string{"level-0", "level-1"}; !reflect.DeepEqual(p.UnlockedLevels, want) {
+		t.Errorf("expected unlocked levels %v but were %v", want, p.UnlockedLevels)
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(p.Secrets, want) {
+		t.Errorf("expected secrets %v but were %v", want, p.Secrets)
+	}
+}
+
+func TestLoadBadSaveFiles(t *testing.T) {
+	dir := tempDir(t)
+	store := save.NewStore(dir)
+
+	for _, content := range []string{`{"version": 99}`, `{"version": 0}`, `not json`} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "slot-1.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Load(1); err == nil {
+			t.Errorf("expected save file %q to be rejected", content)
+		}
+	}
+
+	if _, err := store.Load(save.NUM_SLOTS + 1); err == nil {
+		t.Error("expected an unknown slot to be rejected")
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "mario-save")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}